@@ -0,0 +1,68 @@
+// Copyright 2013, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// bench_test.go [created: Sat, 17 Oct 2026]
+
+package jsontree
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func benchInput(b *testing.B) []byte {
+	p, err := ioutil.ReadFile("testinput/gist.json")
+	if err != nil {
+		b.Fatal(err)
+	}
+	return p
+}
+
+// decodes the benchmark input and reads a few of its values.
+func benchGet(b *testing.B, newTree func() *JsonTree) {
+	p := benchInput(b)
+	b.SetBytes(int64(len(p)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tree := newTree()
+		if err := tree.UnmarshalJSON(p); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := tree.Get("files").Get("example_test.go").Get("filename").String(); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := tree.Get("history").GetIndex(0).Get("user").Get("login").String(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetEager(b *testing.B)   { benchGet(b, New) }
+func BenchmarkGetOrdered(b *testing.B) { benchGet(b, NewOrdered) }
+func BenchmarkGetLazy(b *testing.B)    { benchGet(b, NewLazy) }
+
+// decodes the benchmark input, changes one value and encodes it again.
+func benchRoundTrip(b *testing.B, newTree func() *JsonTree) {
+	p := benchInput(b)
+	b.SetBytes(int64(len(p)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tree := newTree()
+		if err := tree.UnmarshalJSON(p); err != nil {
+			b.Fatal(err)
+		}
+		if err := tree.Get("files").Get("example_test.go").Set("language", NewString("go")); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := tree.MarshalJSON(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRoundTripEager(b *testing.B)   { benchRoundTrip(b, New) }
+func BenchmarkRoundTripOrdered(b *testing.B) { benchRoundTrip(b, NewOrdered) }
+func BenchmarkRoundTripLazy(b *testing.B)    { benchRoundTrip(b, NewLazy) }
@@ -0,0 +1,199 @@
+// Copyright 2013, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// canonical.go [created: Sat, 17 Oct 2026]
+
+package jsontree
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// encodes tree using the JSON Canonicalization Scheme (RFC 8785). object keys
+// are sorted by their UTF-16 code units, numbers are formatted as ECMAScript
+// does for float64 values, and strings escape only the characters JSON
+// requires. the output has no insignificant whitespace, so equal values always
+// encode to the same bytes. returns an error if tree is uninitialized, has an
+// error, or contains a number that is not a finite float64.
+//
+//	https://tools.ietf.org/html/rfc8785
+func (tree *JsonTree) MarshalCanonical() ([]byte, error) {
+	val, err := tree.value()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	err = writeCanonical(&buf, val)
+	if err != nil {
+		return nil, tree.pathError(err)
+	}
+	return buf.Bytes(), nil
+}
+
+// writes the canonical encoding of tree to h and returns the resulting sum.
+func (tree *JsonTree) Hash(h hash.Hash) ([]byte, error) {
+	p, err := tree.MarshalCanonical()
+	if err != nil {
+		return nil, err
+	}
+	h.Write(p)
+	return h.Sum(nil), nil
+}
+
+func writeCanonical(buf *bytes.Buffer, val interface{}) error {
+	switch x := val.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(x))
+	case string:
+		writeCanonicalString(buf, x)
+	case float64:
+		s, err := canonicalNumber(x)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(s)
+	case json.Number:
+		f, err := strconv.ParseFloat(string(x), 64)
+		if err != nil {
+			return fmt.Errorf("number %s overflows float64", x)
+		}
+		s, err := canonicalNumber(f)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(s)
+	case []interface{}:
+		buf.WriteByte('[')
+		for i := range x {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			err := writeCanonical(buf, x[i])
+			if err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case map[string]interface{}, *OrderedObject:
+		m := objectMap(x)
+		buf.WriteByte('{')
+		for i, k := range utf16SortedKeys(m) {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeCanonicalString(buf, k)
+			buf.WriteByte(':')
+			err := writeCanonical(buf, m[k])
+			if err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return fmt.Errorf("unexpected value %T", val)
+	}
+	return nil
+}
+
+// the keys of m sorted by their UTF-16 code units, as RFC 8785 requires.
+func utf16SortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	units := make(map[string][]uint16, len(m))
+	for k := range m {
+		keys = append(keys, k)
+		units[k] = utf16.Encode([]rune(k))
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := units[keys[i]], units[keys[j]]
+		for n := 0; n < len(a) && n < len(b); n++ {
+			if a[n] != b[n] {
+				return a[n] < b[n]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return keys
+}
+
+func writeCanonicalString(buf *bytes.Buffer, s string) {
+	const hex = "0123456789abcdef"
+	buf.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '"', '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			if c < 0x20 {
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hex[c>>4])
+				buf.WriteByte(hex[c&0xf])
+			} else {
+				buf.WriteByte(c)
+			}
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// formats f as ECMAScript's Number.prototype.toString does.
+func canonicalNumber(f float64) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", fmt.Errorf("invalid number %v", f)
+	}
+	if f == 0 {
+		return "0", nil
+	}
+	var sign string
+	if f < 0 {
+		sign = "-"
+		f = -f
+	}
+
+	// the shortest digits that round trip and the position of the
+	// decimal point relative to them.
+	e := strconv.FormatFloat(f, 'e', -1, 64)
+	mant, exp := e[:strings.IndexByte(e, 'e')], e[strings.IndexByte(e, 'e')+1:]
+	digits := strings.Replace(mant, ".", "", 1)
+	x, _ := strconv.Atoi(exp)
+	k, n := len(digits), x+1
+
+	switch {
+	case k <= n && n <= 21:
+		return sign + digits + strings.Repeat("0", n-k), nil
+	case 0 < n && n <= 21:
+		return sign + digits[:n] + "." + digits[n:], nil
+	case -6 < n && n <= 0:
+		return sign + "0." + strings.Repeat("0", -n) + digits, nil
+	}
+	s := sign + digits[:1]
+	if k > 1 {
+		s += "." + digits[1:]
+	}
+	if n-1 >= 0 {
+		return s + "e+" + strconv.Itoa(n-1), nil
+	}
+	return s + "e-" + strconv.Itoa(1-n), nil
+}
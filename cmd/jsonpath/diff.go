@@ -0,0 +1,87 @@
+// Copyright 2013, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// diff.go [created: Sat, 17 Oct 2026]
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/bmatsuo/go-jsontree"
+)
+
+// the diff subcommand. prints the changes between two json files and returns
+// 0 if they are equal, 1 if they differ, and 2 on error.
+func diffMain(args []string) int {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	jsonout := fs.Bool("json", false, "print changes as a json array")
+	sets := fs.Bool("sets", false, "compare arrays as unordered sets")
+	key := fs.String("key", "", "match object elements of arrays compared as sets by this key")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s diff [options] A.json B.json\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	a, err := readTree(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	b, err := readTree(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	opts := jsontree.DiffOptions{UnorderedArrays: *sets || *key != "", ArrayKey: *key}
+	changes, err := opts.Diff(a, b)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if *jsonout {
+		if changes == nil {
+			changes = []jsontree.Change{}
+		}
+		p, err := json.MarshalIndent(changes, "", "\t")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		fmt.Println(string(p))
+	} else {
+		for _, c := range changes {
+			fmt.Println(c)
+		}
+	}
+	if len(changes) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func readTree(filename string) (*jsontree.JsonTree, error) {
+	p, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	js := jsontree.NewOrdered()
+	js.UseNumber()
+	err = js.UnmarshalJSON(p)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return js, nil
+}
@@ -12,6 +12,22 @@ objects from standard input and prints selected data to standard output.
 	"hello"
 	"world"
 
+numbers are printed with the same digits as the input. object keys are printed
+in the order they appear in the input unless -ordered=false is given.
+
+array elements and keys that are not plain identifiers can be selected using
+bracket notation
+
+	$ echo '{"a list":[1,2,3]}' | jsonpath "$['a list'][0]"
+	1
+
+filter expressions select the elements of an array (or values of an object)
+that satisfy a condition. "@" refers to the element being tested and "$" to the
+root of the input.
+
+	$ echo '[{"n":1,"ok":true},{"n":2,"ok":false}]' | jsonpath '$[?(@.ok == true && @.n < 2)].n'
+	1
+
 by default, selected strings are printed as json strings. to print the decoded
 string instead of the json representation use the -decodedstrings option
 
@@ -19,6 +35,10 @@ string instead of the json representation use the -decodedstrings option
 	hello
 	world
 
+when a single path made only of keys, wildcards, indices and slices is given,
+values are selected while the input is read and anything else is skipped
+without being decoded, so very large inputs can be searched quickly.
+
 multiple paths can be selected for each object. these objects can be printed
 on the same line (tab separated) for easier scripting.
 
@@ -27,26 +47,119 @@ on the same line (tab separated) for easier scripting.
 	$ cat test.json | jsonpath -oneline -decodedstrings $.date $.event
 	2012-12-12	apocalypse
 	2012-12-13	false alarm
+
+the -canonical option prints results in the canonical form of RFC 8785, with
+sorted keys and normalized numbers, so equal values print identically and can
+be hashed from the shell.
+
+	$ echo '{"x":{"b":2.0,"a":1}}' | jsonpath -canonical $.x | sha256sum
+
+the diff subcommand compares two json files and prints each difference with
+its path. it exits with status 1 if the files differ.
+
+	$ echo '{"a":1,"b":[1,2]}' > a.json
+	$ echo '{"a":2,"b":[1],"c":true}' > b.json
+	$ jsonpath diff a.json b.json
+	$.a: changed 1 to 2
+	$.b[1]: removed 2
+	$.c: added true
+
+arrays are compared element by element unless -sets is given, and -key matches
+object elements of arrays by the value of a key. the -json option prints the
+changes as a json array.
+
+# Exit Status
+
+jsonpath exits with status 1 if input can not be decoded or, when -mustexist is
+set, a path selects nothing. a path that can not be resolved is reported on
+standard error, with the position of the value in the input, and the exit
+status tells why.
+
+	$ echo '{"port":"80"}' | jsonpath '$.port.number'
+	<stdin>:1:9: not an object (string); $.port.number
+
+	3	a key does not exist
+	4	a value has the wrong type, like a key of an array
+	5	a value is uninitialized
 */
 package main
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"sort"
 
 	"github.com/bmatsuo/go-jsontree"
 	"github.com/bmatsuo/go-jsontree/exp/jsonpath"
 )
 
+// exit statuses for paths that can not be resolved.
+const (
+	exitNoExist = 3 + iota
+	exitTypeError
+	exitUninitialized
+)
+
+// the exit status describing err.
+func exitStatus(err error) int {
+	var typeErr *jsontree.TypeError
+	switch {
+	case errors.Is(err, jsontree.ErrNoExist):
+		return exitNoExist
+	case errors.As(err, &typeErr):
+		return exitTypeError
+	case errors.Is(err, jsontree.ErrUninitialized):
+		return exitUninitialized
+	}
+	return 1
+}
+
+// a reader that records where lines begin so that offsets in its input can
+// be reported as positions.
+type lineReader struct {
+	r        io.Reader
+	filename string
+	n        int64
+	lines    []int64 // offsets at which lines after the first begin
+}
+
+func (r *lineReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	for i := 0; i < n; i++ {
+		if p[i] == '\n' {
+			r.lines = append(r.lines, r.n+int64(i)+1)
+		}
+	}
+	r.n += int64(n)
+	return n, err
+}
+
+// the position of the byte at offset off in the input read so far.
+func (r *lineReader) position(off int64) jsontree.Position {
+	n := sort.Search(len(r.lines), func(i int) bool { return r.lines[i] > off })
+	pos := jsontree.Position{Filename: r.filename, Offset: int(off), Line: n + 1, Column: int(off) + 1}
+	if n > 0 {
+		pos.Column = int(off-r.lines[n-1]) + 1
+	}
+	return pos
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		os.Exit(diffMain(os.Args[2:]))
+	}
+
 	oneline := flag.Bool("oneline", false, "one line printed per input object")
 	onelinesep := flag.String("sep", "\t", "result separator when -oneline is given")
 	decodedstrings := flag.Bool("decodedstrings", false, "don't json encode string results")
 	mustexist := flag.Bool("mustexist", true, "exits with non-zero status if a selector has no results")
 	pretty := flag.Bool("p", false, "pretty-print output")
+	ordered := flag.Bool("ordered", true, "preserve the key order of input objects")
+	canonical := flag.Bool("canonical", false, "print results in canonical form (RFC 8785)")
 	flag.Parse()
 
 	if flag.NArg() < 1 {
@@ -64,27 +177,141 @@ func main() {
 		selectors[i] = sel
 	}
 
-	dec := json.NewDecoder(os.Stdin)
+	in := &lineReader{r: os.Stdin, filename: "<stdin>"}
+	dec := json.NewDecoder(in)
 	exitcode := 0
+
+	// print a selected value
+	first := true
+	output := func(result *jsontree.JsonTree) {
+		// print separators for oneline output
+		if *oneline {
+			if first {
+				first = false
+			} else {
+				fmt.Print(*onelinesep)
+			}
+		}
+
+		// print decoded strings
+		if *decodedstrings {
+			str, err := result.String()
+			if err == nil {
+				if *oneline {
+					fmt.Print(str)
+				} else {
+					fmt.Println(str)
+				}
+				return
+			}
+		}
+
+		// marshal value as json
+		var p []byte
+		var err error
+		if *canonical {
+			p, err = result.MarshalCanonical()
+		} else if *pretty {
+			p, err = json.MarshalIndent(result, "", "\t")
+		} else {
+			p, err = json.Marshal(result)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		} else {
+			if *oneline {
+				fmt.Print(string(p))
+			} else {
+				fmt.Println(string(p))
+			}
+		}
+	}
+
+	// report a path that could not be resolved
+	report := func(err error) {
+		if *mustexist {
+			fmt.Fprintln(os.Stderr, err)
+			exitcode = exitStatus(err)
+		}
+	}
+
+	// a single simple path is selected from the token stream so that values
+	// which are not selected are never decoded
+	if len(paths) == 1 {
+		if sp, err := jsonpath.CompileStream(paths[0]); err == nil {
+			sp.Ordered = *ordered
+			sp.UseNumber = true
+			for {
+				first = true
+				n := 0
+				err := sp.Select(dec, func(r jsonpath.StreamResult) error {
+					n++
+					if r.Err != nil {
+						var pathErr *jsontree.PathError
+						if errors.As(r.Err, &pathErr) {
+							pathErr.Pos = in.position(r.Offset)
+						}
+						report(r.Err)
+						return nil
+					}
+					output(r.Value)
+					return nil
+				})
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					if syntaxErr, ok := err.(*json.SyntaxError); ok {
+						fmt.Fprintf(os.Stderr, "%v: %v\n", in.position(syntaxErr.Offset), err)
+					} else {
+						fmt.Fprintln(os.Stderr, err)
+					}
+					exitcode = 1
+					break
+				}
+				if n == 0 && *mustexist {
+					exitcode = 1
+				}
+				if *oneline {
+					fmt.Println()
+				}
+			}
+			os.Exit(exitcode)
+		}
+	}
+
 	for cont := true; cont; {
-		// read a json object
-		js := new(jsontree.JsonTree)
-		err := dec.Decode(js)
-		switch err {
+		// read a json object, tracking positions for error messages
+		js := jsontree.New()
+		if *ordered {
+			js = jsontree.NewOrdered()
+		}
+		js.UseNumber()
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if err == nil {
+			js.TrackPositions(in.position(dec.InputOffset() - int64(len(raw))))
+			err = js.UnmarshalJSON(raw)
+		}
+		switch err.(type) {
 		case nil:
 			break
-		case io.EOF:
+		case *json.SyntaxError:
+			fmt.Fprintf(os.Stderr, "%v: %v\n", in.position(err.(*json.SyntaxError).Offset), err)
+			exitcode = 1
 			cont = false
 			continue
 		default:
-			fmt.Fprintln(os.Stderr, err)
-			exitcode = 1
+			if err != io.EOF {
+				fmt.Fprintln(os.Stderr, err)
+				exitcode = 1
+			}
 			cont = false
 			continue
 		}
 
 		// apply all selectors
-		first := true
+		first = true
 		for _, sel := range selectors {
 			results := jsonpath.Lookup(js, sel)
 			if len(results) == 0 && *mustexist {
@@ -93,45 +320,11 @@ func main() {
 			}
 
 			for i := range results {
-				// print separators for oneline output
-				if *oneline {
-					if first {
-						first = false
-					} else {
-						fmt.Print(*onelinesep)
-					}
-				}
-
-				// print decoded strings
-				if *decodedstrings {
-					str, err := results[i].String()
-					if err == nil {
-						if *oneline {
-							fmt.Print(str)
-						} else {
-							fmt.Println(str)
-						}
-						continue
-					}
-				}
-
-				// marshal value as json
-				var p []byte
-				var err error
-				if *pretty {
-					p, err = json.MarshalIndent(results[i], "", "\t")
-				} else {
-					p, err = json.Marshal(results[i])
-				}
-				if err != nil {
-					fmt.Fprintln(os.Stderr, err)
-				} else {
-					if *oneline {
-						fmt.Print(string(p))
-					} else {
-						fmt.Println(string(p))
-					}
+				if err := results[i].Err(); err != nil {
+					report(err)
+					continue
 				}
+				output(results[i])
 			}
 		}
 
@@ -0,0 +1,171 @@
+// Copyright 2013, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// compare.go [created: Sat, 17 Oct 2026]
+
+package jsontree
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// reports whether a and b represent the same JSON value. numbers are equal if
+// they have the same value regardless of how they were written or decoded, and
+// objects are equal if they have the same members in any order. trees that
+// are uninitialized or have an error are not equal to anything.
+func Equal(a, b *JsonTree) bool {
+	aval, err := a.value()
+	if err != nil {
+		return false
+	}
+	bval, err := b.value()
+	if err != nil {
+		return false
+	}
+	return compareValues(aval, bval) == 0
+}
+
+// compares a and b, returning -1 if a < b, 0 if a == b, and +1 if a > b.
+// values of different types are ordered null < boolean < number < string <
+// array < object. within a type false < true, numbers are ordered by value,
+// strings bytewise, arrays element by element with shorter prefixes first, and
+// objects as lists of their members sorted by key. trees that are
+// uninitialized or have an error are ordered before all values.
+func Compare(a, b *JsonTree) int {
+	aval, aerr := a.value()
+	bval, berr := b.value()
+	switch {
+	case aerr != nil && berr != nil:
+		return 0
+	case aerr != nil:
+		return -1
+	case berr != nil:
+		return 1
+	}
+	return compareValues(aval, bval)
+}
+
+// reports whether a and b are the same JSON value.
+func valuesEqual(a, b interface{}) bool {
+	return compareValues(a, b) == 0
+}
+
+func compareValues(a, b interface{}) int {
+	ra, rb := valueRank(a), valueRank(b)
+	if ra != rb {
+		return compareInts(ra, rb)
+	}
+	switch a := a.(type) {
+	case bool:
+		return compareBools(a, b.(bool))
+	case float64, json.Number:
+		return compareNumbers(a, b)
+	case string:
+		return strings.Compare(a, b.(string))
+	case []interface{}:
+		b := b.([]interface{})
+		for i := 0; i < len(a) && i < len(b); i++ {
+			if c := compareValues(a[i], b[i]); c != 0 {
+				return c
+			}
+		}
+		return compareInts(len(a), len(b))
+	case map[string]interface{}, *OrderedObject:
+		return compareObjects(a, b)
+	}
+	return 0
+}
+
+// the position of a value's type in the total order.
+func valueRank(val interface{}) int {
+	switch val.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case float64, json.Number:
+		return 2
+	case string:
+		return 3
+	case []interface{}:
+		return 4
+	case map[string]interface{}, *OrderedObject:
+		return 5
+	}
+	return -1
+}
+
+func compareObjects(a, b interface{}) int {
+	amap, bmap := objectMap(a), objectMap(b)
+	// the keys of plain maps are sorted
+	akeys, bkeys := objectKeys(amap), objectKeys(bmap)
+	for i := 0; i < len(akeys) && i < len(bkeys); i++ {
+		if c := strings.Compare(akeys[i], bkeys[i]); c != 0 {
+			return c
+		}
+		if c := compareValues(amap[akeys[i]], bmap[bkeys[i]]); c != 0 {
+			return c
+		}
+	}
+	return compareInts(len(akeys), len(bkeys))
+}
+
+// compares two numbers exactly when both were decoded with UseNumber,
+// otherwise as float64.
+func compareNumbers(a, b interface{}) int {
+	an, aok := a.(json.Number)
+	bn, bok := b.(json.Number)
+	if aok && bok && exponentInRange(string(an)) && exponentInRange(string(bn)) {
+		x, xok := new(big.Rat).SetString(string(an))
+		y, yok := new(big.Rat).SetString(string(bn))
+		if xok && yok {
+			return x.Cmp(y)
+		}
+	}
+	x, y := toFloat64(a), toFloat64(b)
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	case math.IsNaN(x) || math.IsNaN(y):
+		return compareBools(!math.IsNaN(x), !math.IsNaN(y))
+	}
+	return 0
+}
+
+func toFloat64(val interface{}) float64 {
+	switch x := val.(type) {
+	case float64:
+		return x
+	case json.Number:
+		f, _ := strconv.ParseFloat(string(x), 64)
+		return f
+	}
+	return 0
+}
+
+func compareBools(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case b:
+		return -1
+	}
+	return 1
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,446 @@
+// Copyright 2013, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// decode.go [created: Sat, 17 Oct 2026]
+
+package jsontree
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// stores the value of tree in the value pointed to by v, following the rules
+// of json.Unmarshal. struct fields are matched to object keys using their json
+// tags, preferring an exact match to a case-insensitive one, and unknown keys
+// are ignored. fields tagged with the string option are decoded from JSON
+// strings holding their values. a *JsonTree or JsonTree destination receives a
+// copy of the subtree, and interface{} destinations receive maps, slices and
+// scalars as json.Unmarshal would produce them, with numbers kept as
+// json.Number if the tree was decoded with UseNumber. returns a *PathError
+// locating the value that could not be stored.
+func (tree *JsonTree) Decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("decode requires a non-nil pointer (%T)", v)
+	}
+	if _, err := tree.value(); err != nil {
+		return err
+	}
+	return tree.decode(rv.Elem())
+}
+
+var (
+	treeType            = reflect.TypeOf(JsonTree{})
+	numberType          = reflect.TypeOf(json.Number(""))
+	unmarshalerType     = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+func (tree *JsonTree) decode(v reflect.Value) error {
+	// as with json.Unmarshal, null sets pointers, interfaces, maps and slices
+	// to nil but is passed to the UnmarshalJSON method of other values.
+	if tree.typ == Null && v.Type() != treeType {
+		switch {
+		case v.Kind() == reflect.Interface, v.Kind() == reflect.Ptr:
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		case !reflect.PtrTo(v.Type()).Implements(unmarshalerType):
+			if v.Kind() == reflect.Map || v.Kind() == reflect.Slice {
+				v.Set(reflect.Zero(v.Type()))
+			}
+			return nil
+		}
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	if v.Type() == treeType {
+		v.Set(reflect.ValueOf(tree.Copy()).Elem())
+		return nil
+	}
+	if v.Type() == numberType {
+		if tree.typ != Number {
+			return tree.pathError(&TypeError{Expected: Number, Actual: tree.typ})
+		}
+		v.SetString(tree.numberString())
+		return nil
+	}
+	if reflect.PtrTo(v.Type()).Implements(unmarshalerType) {
+		p, err := json.Marshal(tree.val)
+		if err == nil {
+			err = v.Addr().Interface().(json.Unmarshaler).UnmarshalJSON(p)
+		}
+		if err != nil {
+			return tree.pathError(err)
+		}
+		return nil
+	}
+	if tree.typ == String && reflect.PtrTo(v.Type()).Implements(textUnmarshalerType) {
+		err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(tree.val.(string)))
+		if err != nil {
+			return tree.pathError(err)
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() > 0 {
+			return tree.pathErrorf("can not decode into %v", v.Type())
+		}
+		if tree.val == nil {
+			v.Set(reflect.Zero(v.Type()))
+		} else {
+			v.Set(reflect.ValueOf(plainValue(tree.val)))
+		}
+	case reflect.Bool:
+		b, err := tree.Boolean()
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.String:
+		s, err := tree.String()
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x, err := tree.Int64()
+		if err != nil {
+			return err
+		}
+		if v.OverflowInt(x) {
+			return tree.pathErrorf("number %s overflows %v", tree.numberString(), v.Type())
+		}
+		v.SetInt(x)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		x, err := tree.Uint64()
+		if err != nil {
+			return err
+		}
+		if v.OverflowUint(x) {
+			return tree.pathErrorf("number %s overflows %v", tree.numberString(), v.Type())
+		}
+		v.SetUint(x)
+	case reflect.Float32, reflect.Float64:
+		x, err := tree.Float64()
+		if err != nil {
+			return err
+		}
+		if v.OverflowFloat(x) {
+			return tree.pathErrorf("number %s overflows %v", tree.numberString(), v.Type())
+		}
+		v.SetFloat(x)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 && tree.typ == String {
+			p, err := base64.StdEncoding.DecodeString(tree.val.(string))
+			if err != nil {
+				return tree.pathError(err)
+			}
+			v.SetBytes(p)
+			return nil
+		}
+		if err := tree.checkType(Array); err != nil {
+			return err
+		}
+		n := len(tree.val.([]interface{}))
+		s := reflect.MakeSlice(v.Type(), n, n)
+		for i := 0; i < n; i++ {
+			if err := tree.GetIndex(i).decode(s.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+	case reflect.Array:
+		if err := tree.checkType(Array); err != nil {
+			return err
+		}
+		n := len(tree.val.([]interface{}))
+		for i := 0; i < v.Len(); i++ {
+			if i >= n {
+				v.Index(i).Set(reflect.Zero(v.Type().Elem()))
+				continue
+			}
+			if err := tree.GetIndex(i).decode(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if err := tree.checkType(Object); err != nil {
+			return err
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		for _, k := range objectKeys(tree.val) {
+			child := tree.Get(k)
+			key, err := mapKey(v.Type().Key(), k)
+			if err != nil {
+				return child.pathError(err)
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := child.decode(elem); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, elem)
+		}
+	case reflect.Struct:
+		if err := tree.checkType(Object); err != nil {
+			return err
+		}
+		fields := cachedFields(v.Type())
+		for _, k := range objectKeys(tree.val) {
+			f := fields.find(k)
+			if f == nil {
+				continue
+			}
+			child, fv := tree.Get(k), fieldByIndex(v, f.index)
+			if f.quoted && child.typ != Null {
+				if err := child.decodeQuoted(fv); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := child.decode(fv); err != nil {
+				return err
+			}
+		}
+	default:
+		return tree.pathErrorf("can not decode into %v", v.Type())
+	}
+	return nil
+}
+
+// decodes the value held in the JSON string tree into v, for fields with the
+// string option.
+func (tree *JsonTree) decodeQuoted(v reflect.Value) error {
+	s, err := tree.String()
+	if err != nil {
+		return tree.pathErrorf("invalid use of ,string struct tag, trying to decode unquoted value into %v", v.Type())
+	}
+	quoted := New()
+	quoted.UseNumber()
+	if err = quoted.UnmarshalJSON([]byte(s)); err == nil {
+		err = quoted.decode(v)
+	}
+	if err != nil {
+		return tree.pathErrorf("invalid use of ,string struct tag, trying to decode %q into %v", s, v.Type())
+	}
+	return nil
+}
+
+// converts an object key to a value of the map key type t.
+func mapKey(t reflect.Type, k string) (reflect.Value, error) {
+	if reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		key := reflect.New(t)
+		err := key.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(k))
+		return key.Elem(), err
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(k).Convert(t), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x, err := strconv.ParseInt(k, 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("invalid %v key %q", t, k)
+		}
+		return reflect.ValueOf(x).Convert(t), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		x, err := strconv.ParseUint(k, 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("invalid %v key %q", t, k)
+		}
+		return reflect.ValueOf(x).Convert(t), nil
+	}
+	return reflect.Value{}, fmt.Errorf("can not decode object keys into %v", t)
+}
+
+// a deep copy of val with ordered objects converted to maps.
+func plainValue(val interface{}) interface{} {
+	switch val := val.(type) {
+	case []interface{}:
+		a := make([]interface{}, len(val))
+		for i := range val {
+			a[i] = plainValue(val[i])
+		}
+		return a
+	case map[string]interface{}, *OrderedObject:
+		m := objectMap(val)
+		plain := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			plain[k] = plainValue(v)
+		}
+		return plain
+	}
+	return val
+}
+
+// a struct field that can be encoded and decoded.
+type field struct {
+	name      string
+	index     []int
+	tagged    bool
+	omitEmpty bool
+	quoted    bool // the string option on a field of scalar type
+}
+
+type fieldList []field
+
+// the field named key, or the first field whose name matches key ignoring
+// case.
+func (fields fieldList) find(key string) *field {
+	var fold *field
+	for i := range fields {
+		if fields[i].name == key {
+			return &fields[i]
+		}
+		if fold == nil && strings.EqualFold(fields[i].name, key) {
+			fold = &fields[i]
+		}
+	}
+	return fold
+}
+
+var fieldCache sync.Map // map[reflect.Type]fieldList
+
+func cachedFields(t reflect.Type) fieldList {
+	if fields, ok := fieldCache.Load(t); ok {
+		return fields.(fieldList)
+	}
+	fields, _ := fieldCache.LoadOrStore(t, typeFields(t))
+	return fields.(fieldList)
+}
+
+// the exported fields of the struct type t. fields of embedded structs are
+// promoted unless a shallower field has the same name. of several fields with
+// the same name at the same depth only a single tagged one is kept, otherwise
+// none are, as with encoding/json.
+func typeFields(t reflect.Type) fieldList {
+	var fields fieldList
+	seen := make(map[string]bool)
+	type embedded struct {
+		typ   reflect.Type
+		index []int
+	}
+	next := []embedded{{t, nil}}
+	visited := map[reflect.Type]bool{}
+	for len(next) > 0 {
+		current := next
+		next = nil
+		var level fieldList
+		for _, e := range current {
+			if visited[e.typ] {
+				continue
+			}
+			visited[e.typ] = true
+			for i := 0; i < e.typ.NumField(); i++ {
+				sf := e.typ.Field(i)
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, opts := tag, ""
+				if j := strings.IndexByte(tag, ','); j >= 0 {
+					name, opts = tag[:j], tag[j:]
+				}
+				index := make([]int, len(e.index)+1)
+				copy(index, e.index)
+				index[len(e.index)] = i
+				ft := sf.Type
+				if ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+					if sf.PkgPath != "" && sf.Type.Kind() == reflect.Ptr {
+						// can not allocate an unexported pointer
+						continue
+					}
+					next = append(next, embedded{ft, index})
+					continue
+				}
+				if sf.PkgPath != "" {
+					continue
+				}
+				tagged := name != ""
+				if !tagged {
+					name = sf.Name
+				}
+				level = append(level, field{
+					name:      name,
+					index:     index,
+					tagged:    tagged,
+					omitEmpty: strings.Contains(opts+",", ",omitempty,"),
+					quoted:    strings.Contains(opts+",", ",string,") && isQuotable(ft),
+				})
+			}
+		}
+		for _, f := range level {
+			if seen[f.name] {
+				continue
+			}
+			seen[f.name] = true
+			if f, ok := dominantField(level, f.name); ok {
+				fields = append(fields, f)
+			}
+		}
+	}
+	return fields
+}
+
+// the field named name among fields of the same depth, if there is just one
+// or just one of them is tagged.
+func dominantField(level fieldList, name string) (field, bool) {
+	var dominant field
+	n, ntagged := 0, 0
+	for _, f := range level {
+		if f.name != name {
+			continue
+		}
+		if n++; n == 1 || (f.tagged && ntagged == 0) {
+			dominant = f
+		}
+		if f.tagged {
+			ntagged++
+		}
+	}
+	return dominant, n == 1 || ntagged == 1
+}
+
+// reports whether the string option applies to fields of type t.
+func isQuotable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// the field of v at index, allocating nil embedded struct pointers.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
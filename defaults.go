@@ -0,0 +1,50 @@
+// Copyright 2013, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// defaults.go [created: Sat, 17 Oct 2026]
+
+package jsontree
+
+import (
+	"errors"
+)
+
+// like String but returns def if tree is null or does not exist because a key
+// or index was missing. other errors, like a value of the wrong type, are
+// returned.
+func (tree *JsonTree) StringOr(def string) (string, error) {
+	if tree.isAbsent() {
+		return def, nil
+	}
+	return tree.String()
+}
+
+// like Number but returns def if tree is null or does not exist because a key
+// or index was missing. other errors, like a value of the wrong type, are
+// returned.
+func (tree *JsonTree) NumberOr(def float64) (float64, error) {
+	if tree.isAbsent() {
+		return def, nil
+	}
+	return tree.Number()
+}
+
+// like Boolean but returns def if tree is null or does not exist because a
+// key or index was missing. other errors, like a value of the wrong type, are
+// returned.
+func (tree *JsonTree) BooleanOr(def bool) (bool, error) {
+	if tree.isAbsent() {
+		return def, nil
+	}
+	return tree.Boolean()
+}
+
+// reports whether tree is null or was not found in its parent.
+func (tree *JsonTree) isAbsent() bool {
+	if tree.typ == Null {
+		return true
+	}
+	err := tree.Err()
+	return errors.Is(err, ErrNoExist) || errors.Is(err, ErrIndexOutOfRange)
+}
@@ -0,0 +1,209 @@
+// Copyright 2013, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// diff.go [created: Sat, 17 Oct 2026]
+
+package jsontree
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// the kind of a Change found by Diff.
+type ChangeKind uint8
+
+const (
+	Added       ChangeKind = iota // a value exists only in the new tree
+	Removed                       // a value exists only in the old tree
+	Changed                       // a value of the same type differs
+	TypeChanged                   // a value has a different type
+)
+
+var changeKindStrings = []string{
+	Added:       "added",
+	Removed:     "removed",
+	Changed:     "changed",
+	TypeChanged: "type changed",
+}
+
+func (k ChangeKind) String() string {
+	if int(k) >= len(changeKindStrings) {
+		return fmt.Sprintf("Unknown (%d)", k)
+	}
+	return changeKindStrings[k]
+}
+
+// implements encoding.TextMarshaler
+func (k ChangeKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// a difference between two trees. Path is the JSONPath of the affected node
+// in the old tree, or in the new tree for Added changes. Old is nil for Added
+// changes and New is nil for Removed changes. Old and New refer to nodes of
+// the trees given to Diff.
+type Change struct {
+	Kind ChangeKind `json:"kind"`
+	Path string     `json:"path"`
+	Old  *JsonTree  `json:"old,omitempty"`
+	New  *JsonTree  `json:"new,omitempty"`
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("%s: added %s", c.Path, marshalString(c.New))
+	case Removed:
+		return fmt.Sprintf("%s: removed %s", c.Path, marshalString(c.Old))
+	case TypeChanged:
+		return fmt.Sprintf("%s: changed %v %s to %v %s", c.Path,
+			c.Old.Type(), marshalString(c.Old), c.New.Type(), marshalString(c.New))
+	}
+	return fmt.Sprintf("%s: changed %s to %s", c.Path, marshalString(c.Old), marshalString(c.New))
+}
+
+func marshalString(tree *JsonTree) string {
+	p, err := json.Marshal(tree)
+	if err != nil {
+		return fmt.Sprintf("<%v>", err)
+	}
+	return string(p)
+}
+
+// options controlling how Diff compares arrays. the zero value compares arrays
+// as ordered lists.
+type DiffOptions struct {
+	// compare arrays as sets. elements are matched regardless of position
+	// and only unmatched elements are reported.
+	UnorderedArrays bool
+
+	// when comparing arrays as sets, object elements having this key are
+	// matched by its value and then compared member by member. other
+	// elements are matched only by equality.
+	ArrayKey string
+}
+
+// the differences between a and b, comparing arrays as ordered lists. returns
+// a *PathError if a or b is uninitialized or has an error.
+func Diff(a, b *JsonTree) ([]Change, error) {
+	return DiffOptions{}.Diff(a, b)
+}
+
+// the differences between a and b according to opts. object members are
+// visited in the order of a's keys followed by keys only present in b.
+func (opts DiffOptions) Diff(a, b *JsonTree) ([]Change, error) {
+	if _, err := a.value(); err != nil {
+		return nil, err
+	}
+	if _, err := b.value(); err != nil {
+		return nil, err
+	}
+	var changes []Change
+	opts.diff(a, b, &changes)
+	return changes, nil
+}
+
+func (opts DiffOptions) diff(a, b *JsonTree, changes *[]Change) {
+	if a.Type() != b.Type() {
+		*changes = append(*changes, Change{TypeChanged, a.path(), a, b})
+		return
+	}
+	switch a.Type() {
+	case Object:
+		opts.diffObjects(a, b, changes)
+	case Array:
+		if opts.UnorderedArrays {
+			opts.diffSets(a, b, changes)
+		} else {
+			opts.diffArrays(a, b, changes)
+		}
+	default:
+		if !valuesEqual(a.val, b.val) {
+			*changes = append(*changes, Change{Changed, a.path(), a, b})
+		}
+	}
+}
+
+func (opts DiffOptions) diffObjects(a, b *JsonTree, changes *[]Change) {
+	bmap := objectMap(b.val)
+	for _, k := range objectKeys(a.val) {
+		if _, ok := bmap[k]; !ok {
+			old := a.Get(k)
+			*changes = append(*changes, Change{Removed, old.path(), old, nil})
+			continue
+		}
+		opts.diff(a.Get(k), b.Get(k), changes)
+	}
+	amap := objectMap(a.val)
+	for _, k := range objectKeys(b.val) {
+		if _, ok := amap[k]; !ok {
+			added := b.Get(k)
+			*changes = append(*changes, Change{Added, added.path(), nil, added})
+		}
+	}
+}
+
+func (opts DiffOptions) diffArrays(a, b *JsonTree, changes *[]Change) {
+	alen, blen := len(a.val.([]interface{})), len(b.val.([]interface{}))
+	for i := 0; i < alen && i < blen; i++ {
+		opts.diff(a.GetIndex(i), b.GetIndex(i), changes)
+	}
+	for i := blen; i < alen; i++ {
+		old := a.GetIndex(i)
+		*changes = append(*changes, Change{Removed, old.path(), old, nil})
+	}
+	for i := alen; i < blen; i++ {
+		added := b.GetIndex(i)
+		*changes = append(*changes, Change{Added, added.path(), nil, added})
+	}
+}
+
+func (opts DiffOptions) diffSets(a, b *JsonTree, changes *[]Change) {
+	aval, bval := a.val.([]interface{}), b.val.([]interface{})
+	matched := make([]bool, len(bval))
+	match := func(i int) int {
+		key, keyed := opts.elementKey(aval[i])
+		for j := range bval {
+			if matched[j] {
+				continue
+			}
+			if keyed {
+				if bkey, ok := opts.elementKey(bval[j]); ok && valuesEqual(key, bkey) {
+					return j
+				}
+			} else if valuesEqual(aval[i], bval[j]) {
+				return j
+			}
+		}
+		return -1
+	}
+	for i := range aval {
+		j := match(i)
+		if j < 0 {
+			old := a.GetIndex(i)
+			*changes = append(*changes, Change{Removed, old.path(), old, nil})
+			continue
+		}
+		matched[j] = true
+		if _, keyed := opts.elementKey(aval[i]); keyed {
+			opts.diff(a.GetIndex(i), b.GetIndex(j), changes)
+		}
+	}
+	for j := range bval {
+		if !matched[j] {
+			added := b.GetIndex(j)
+			*changes = append(*changes, Change{Added, added.path(), nil, added})
+		}
+	}
+}
+
+// the value of the ArrayKey member of an array element, if it has one.
+func (opts DiffOptions) elementKey(val interface{}) (interface{}, bool) {
+	if opts.ArrayKey == "" || !isObject(val) {
+		return nil, false
+	}
+	key, ok := objectMap(val)[opts.ArrayKey]
+	return key, ok
+}
@@ -0,0 +1,62 @@
+// Copyright 2013, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// each.go [created: Sat, 17 Oct 2026]
+
+package jsontree
+
+// calls fn with each member of the object tree, in the order given by Keys,
+// or each element of the array tree. index is -1 for object members and key
+// is empty for array elements. each child has tree as its parent. iteration
+// stops at the first error returned by fn, which Each returns. returns a
+// *PathError wrapping a *TypeError, with Expected set to Object, if tree is not
+// an object or an array.
+func (tree *JsonTree) Each(fn func(key string, index int, child *JsonTree) error) error {
+	if err := tree.checkContainer(); err != nil {
+		return err
+	}
+	if tree.typ == Array {
+		for i := range tree.val.([]interface{}) {
+			if err := fn("", i, tree.GetIndex(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, k := range objectKeys(tree.val) {
+		if err := fn(k, -1, tree.Get(k)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// the members of the object tree, in the order given by Keys, or the
+// elements of the array tree. returns a *PathError wrapping a *TypeError if
+// tree is not an object or an array.
+func (tree *JsonTree) Children() ([]*JsonTree, error) {
+	var children []*JsonTree
+	err := tree.Each(func(key string, index int, child *JsonTree) error {
+		children = append(children, child)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return children, nil
+}
+
+// returns a *PathError wrapping a *TypeError if tree is not an object or an
+// array.
+func (tree *JsonTree) checkContainer() error {
+	switch {
+	case !tree.init:
+		return tree.pathError(ErrUninitialized)
+	case tree.typ == Error:
+		return *tree.err
+	case tree.typ != Object && tree.typ != Array:
+		return tree.pathError(&TypeError{Expected: Object, Actual: tree.typ})
+	}
+	return tree.load()
+}
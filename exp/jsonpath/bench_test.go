@@ -0,0 +1,155 @@
+// Copyright 2013, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// bench_test.go [created: Sat, 17 Oct 2026]
+
+package jsonpath
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/bmatsuo/go-jsontree"
+)
+
+// the goroutine and channel based engine that Selector replaced. kept here to
+// benchmark against.
+
+func chanLookup(js *jsontree.JsonTree, sel ChanSelector) []*jsontree.JsonTree {
+	var selected []*jsontree.JsonTree
+	jschan := make(chan *jsontree.JsonTree, 2)
+	go sel(jschan, js)
+	for js := range jschan {
+		if js == nil {
+			break
+		}
+		selected = append(selected, js)
+	}
+	return selected
+}
+
+func chanChain(path ...ChanSelector) ChanSelector {
+	return func(out chan<- *jsontree.JsonTree, js *jsontree.JsonTree) {
+		cin := make(chan *jsontree.JsonTree, 2)
+		cin <- js
+		cin <- nil
+		cout := make(chan *jsontree.JsonTree, 2)
+		chain := func(i int, cout chan<- *jsontree.JsonTree, cin <-chan *jsontree.JsonTree) {
+			for js := range cin {
+				if js == nil {
+					break
+				}
+				_cout := make(chan *jsontree.JsonTree)
+				go path[i](_cout, js)
+				for js := range _cout {
+					if js == nil {
+						break
+					}
+					cout <- js
+				}
+			}
+			cout <- nil
+		}
+		for i := range path {
+			if i == len(path)-1 {
+				go chain(i, out, cin)
+			} else {
+				go chain(i, cout, cin)
+				cin = cout
+				cout = make(chan *jsontree.JsonTree, 2)
+			}
+		}
+	}
+}
+
+func chanRecursiveDescent(out chan<- *jsontree.JsonTree, js *jsontree.JsonTree) {
+	chanRecDescent(out, js)
+	out <- nil
+}
+
+func chanRecDescent(out chan<- *jsontree.JsonTree, js *jsontree.JsonTree) {
+	out <- js
+	if a, err := js.Array(); err == nil {
+		for i := range a {
+			chanRecDescent(out, js.GetIndex(i))
+		}
+	} else if m, err := js.Object(); err == nil {
+		for k := range m {
+			chanRecDescent(out, js.Get(k))
+		}
+	}
+}
+
+func chanKey(key string) ChanSelector {
+	return func(out chan<- *jsontree.JsonTree, js *jsontree.JsonTree) {
+		jschild := js.Get(key)
+		if js.Err() == nil {
+			out <- jschild
+		}
+		out <- nil
+	}
+}
+
+func benchInput(b *testing.B) *jsontree.JsonTree {
+	p, err := ioutil.ReadFile("../../testinput/gist.json")
+	if err != nil {
+		b.Fatal(err)
+	}
+	js := jsontree.New()
+	err = js.UnmarshalJSON(p)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return js
+}
+
+func BenchmarkLookupKeys(b *testing.B) {
+	js := benchInput(b)
+	sel := Chain(Key("files"), Key("example_test.go"), Key("filename"))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Lookup(js, sel)
+	}
+}
+
+func BenchmarkChanLookupKeys(b *testing.B) {
+	js := benchInput(b)
+	sel := chanChain(chanKey("files"), chanKey("example_test.go"), chanKey("filename"))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		chanLookup(js, sel)
+	}
+}
+
+func BenchmarkLookupRecursiveDescent(b *testing.B) {
+	js := benchInput(b)
+	sel := Chain(RecursiveDescent, Key("login"))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Lookup(js, sel)
+	}
+}
+
+func BenchmarkChanLookupRecursiveDescent(b *testing.B) {
+	js := benchInput(b)
+	sel := chanChain(chanRecursiveDescent, chanKey("login"))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		chanLookup(js, sel)
+	}
+}
+
+func BenchmarkFirstRecursiveDescent(b *testing.B) {
+	js := benchInput(b)
+	sel := Chain(RecursiveDescent, Key("login"), IgnoreErrors)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		First(js, sel)
+	}
+}
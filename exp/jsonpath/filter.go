@@ -0,0 +1,153 @@
+// Copyright 2013, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// filter.go [created: Sat, 17 Oct 2026]
+
+package jsonpath
+
+import (
+	"github.com/bmatsuo/go-jsontree"
+)
+
+// the operator of a Compare selector.
+type Comparison uint8
+
+const (
+	Equal Comparison = iota
+	NotEqual
+	Less
+	LessEqual
+	Greater
+	GreaterEqual
+)
+
+var comparisonStrings = []string{
+	Equal:        "==",
+	NotEqual:     "!=",
+	Less:         "<",
+	LessEqual:    "<=",
+	Greater:      ">",
+	GreaterEqual: ">=",
+}
+
+func (cmp Comparison) String() string {
+	if int(cmp) >= len(comparisonStrings) {
+		return "?"
+	}
+	return comparisonStrings[cmp]
+}
+
+// selects the elements of an array or the values of an object for which pred
+// selects anything. pred is typically built from Has, Compare, And, Or and Not.
+func Filter(pred ...Selector) Selector {
+	return Chain(All, Has(pred...))
+}
+
+// a selector that always selects js, ignoring its input. used for literal
+// operands of Compare.
+func Value(js *jsontree.JsonTree) Selector {
+	return func(s *Search, _ *jsontree.JsonTree, yield func(*jsontree.JsonTree) bool) bool {
+		return yield(js)
+	}
+}
+
+// selects its input if the values selected by lhs and rhs satisfy cmp. an
+// operand which selects nothing (or more than one value) is absent. absent
+// operands are equal to each other and unequal to everything else. ordering
+// comparisons are only true between two numbers or two strings.
+func Compare(cmp Comparison, lhs, rhs Selector) Selector {
+	return func(s *Search, js *jsontree.JsonTree, yield func(*jsontree.JsonTree) bool) bool {
+		if compare(cmp, operand(s, js, lhs), operand(s, js, rhs)) {
+			return yield(js)
+		}
+		return true
+	}
+}
+
+// selects its input if every selector selects something.
+func And(sel ...Selector) Selector {
+	return func(s *Search, js *jsontree.JsonTree, yield func(*jsontree.JsonTree) bool) bool {
+		for i := range sel {
+			if s.first(js, sel[i:i+1]) == nil {
+				return true
+			}
+		}
+		return yield(js)
+	}
+}
+
+// selects its input if any selector selects something.
+func Or(sel ...Selector) Selector {
+	return func(s *Search, js *jsontree.JsonTree, yield func(*jsontree.JsonTree) bool) bool {
+		for i := range sel {
+			if s.first(js, sel[i:i+1]) != nil {
+				return yield(js)
+			}
+		}
+		return true
+	}
+}
+
+// selects its input if the path sel selects nothing.
+func Not(sel ...Selector) Selector {
+	return func(s *Search, js *jsontree.JsonTree, yield func(*jsontree.JsonTree) bool) bool {
+		if s.first(js, sel) == nil {
+			return yield(js)
+		}
+		return true
+	}
+}
+
+// the single value selected by sel from js. nil if the value is absent.
+func operand(s *Search, js *jsontree.JsonTree, sel Selector) *jsontree.JsonTree {
+	var val *jsontree.JsonTree
+	n := 0
+	sel(s, js, func(js *jsontree.JsonTree) bool {
+		if js.Err() == nil {
+			val = js
+			n++
+		}
+		return n < 2
+	})
+	if n != 1 {
+		return nil
+	}
+	return val
+}
+
+func compare(cmp Comparison, a, b *jsontree.JsonTree) bool {
+	switch cmp {
+	case Equal:
+		return equal(a, b)
+	case NotEqual:
+		return !equal(a, b)
+	case Less:
+		return less(a, b)
+	case LessEqual:
+		return less(a, b) || equal(a, b)
+	case Greater:
+		return less(b, a)
+	case GreaterEqual:
+		return less(b, a) || equal(a, b)
+	}
+	return false
+}
+
+func equal(a, b *jsontree.JsonTree) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return jsontree.Equal(a, b)
+}
+
+func less(a, b *jsontree.JsonTree) bool {
+	if a == nil || b == nil || a.Type() != b.Type() {
+		return false
+	}
+	switch a.Type() {
+	case jsontree.Number, jsontree.String:
+		return jsontree.Compare(a, b) < 0
+	}
+	return false
+}
@@ -10,6 +10,18 @@ generally conforms with the dot-syntax of JSONPath
 
 	http://goessner.net/articles/JsonPath/
 
+along with the bracket-syntax for array indices, slices and quoted keys
+
+	$.items[0]['quoted key']
+	$.items[-1]
+	$.items[1:5:2]
+	$.user['id','email']
+
+and filter expressions using comparisons (==, !=, <, <=, >, >=), existence
+tests and the logical operators &&, || and !
+
+	$.items[?(@.price < 10 && !@.discontinued)]
+
 Warning
 
 jsonpath is an experimental package and it's API is subject to change without
@@ -18,213 +30,344 @@ notice.
 package jsonpath
 
 import (
+	"math"
+
 	"github.com/bmatsuo/go-jsontree"
 )
 
+// returns every tree selected by path from js.
 func Lookup(js *jsontree.JsonTree, path ...Selector) []*jsontree.JsonTree {
 	var selected []*jsontree.JsonTree
-	jschan := make(chan *jsontree.JsonTree, 2)
-	switch len(path) {
-	case 0:
+	if len(path) == 0 {
 		return nil
-	case 1:
-		go path[0](jschan, js)
-	default:
-		go Chain(path...)(jschan, js)
 	}
-	for js := range jschan {
-		if js == nil {
-			break
-		}
+	Chain(path...)(new(Search), js, func(js *jsontree.JsonTree) bool {
 		selected = append(selected, js)
-	}
+		return true
+	})
 	return selected
 }
 
-// Selectors MUST send nil on the channel when there are no more elements.
-type Selector func(chan<- *jsontree.JsonTree, *jsontree.JsonTree)
+// returns the first tree selected by path from js, or nil if nothing is
+// selected. selection stops as soon as a tree is found.
+func First(js *jsontree.JsonTree, path ...Selector) *jsontree.JsonTree {
+	return new(Search).first(js, path)
+}
 
-func Chain(path ...Selector) Selector {
-	return func(out chan<- *jsontree.JsonTree, js *jsontree.JsonTree) {
-		cin := make(chan *jsontree.JsonTree, 2)
-		cin <- js
-		cin <- nil
-		cout := make(chan *jsontree.JsonTree, 2)
-		chain := func(i int, cout chan<- *jsontree.JsonTree, cin <-chan *jsontree.JsonTree) {
-			j := 0
-			for js := range cin {
-				if js == nil {
-					j++
-					break
-				}
-				_cout := make(chan *jsontree.JsonTree)
-				go path[i](_cout, js)
-				for js := range _cout {
-					if js == nil {
-						break
-					}
-					cout <- js
-				}
-			}
-			cout <- nil
-		}
-		for i := range path {
-			if i == len(path)-1 {
-				go chain(i, out, cin)
-			} else {
-				go chain(i, cout, cin)
-				cin = cout
-				cout = make(chan *jsontree.JsonTree, 2)
-			}
+func (s *Search) first(js *jsontree.JsonTree, path []Selector) *jsontree.JsonTree {
+	var first *jsontree.JsonTree
+	if len(path) == 0 {
+		return nil
+	}
+	Chain(path...)(s, js, func(js *jsontree.JsonTree) bool {
+		first = js
+		return false
+	})
+	return first
+}
+
+// returns an iterator over the trees selected by path from js. the selectors
+// are run as the iterator is consumed, so breaking out of a range loop stops
+// selection.
+//
+//	for js := range jsonpath.Select(js, sel) {
+//		...
+//	}
+func Select(js *jsontree.JsonTree, path ...Selector) func(yield func(*jsontree.JsonTree) bool) {
+	return func(yield func(*jsontree.JsonTree) bool) {
+		if len(path) > 0 {
+			Chain(path...)(new(Search), js, yield)
 		}
 	}
 }
 
-func Count(path ...Selector) Selector {
-	return func(out chan<- *jsontree.JsonTree, js *jsontree.JsonTree) {
-		countch := make(chan *jsontree.JsonTree)
-		count := 0
-		go Chain(path...)(countch, js)
-		for js := range countch {
+// a Selector calls yield with each tree it selects from js, in order, on the
+// caller's goroutine. if yield returns false the Selector must stop and
+// return false. otherwise it returns true once every tree has been yielded.
+// a Selector that runs other selectors passes them s.
+type Selector func(s *Search, js *jsontree.JsonTree, yield func(*jsontree.JsonTree) bool) bool
+
+// the channel based signature used for Selectors by earlier versions of this
+// package. ChanSelectors MUST send nil on the channel when there are no more
+// elements.
+type ChanSelector func(chan<- *jsontree.JsonTree, *jsontree.JsonTree)
+
+// adapts a ChanSelector to a Selector. sel runs in its own goroutine. if
+// selection stops early the remaining output of sel is discarded in the
+// background so that sel can finish.
+func FromChan(sel ChanSelector) Selector {
+	return func(s *Search, js *jsontree.JsonTree, yield func(*jsontree.JsonTree) bool) bool {
+		out := make(chan *jsontree.JsonTree, 2)
+		go sel(out, js)
+		for js := range out {
 			if js == nil {
-				break
+				return true
 			}
-			count++
+			if !yield(js) {
+				go drain(out)
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func drain(c <-chan *jsontree.JsonTree) {
+	for js := range c {
+		if js == nil {
+			return
 		}
-		out <- jsontree.NewNumber(float64(count))
+	}
+}
+
+// adapts a Selector to a ChanSelector for code written against the channel
+// based interface.
+func ToChan(sel Selector) ChanSelector {
+	return func(out chan<- *jsontree.JsonTree, js *jsontree.JsonTree) {
+		sel(new(Search), js, func(js *jsontree.JsonTree) bool {
+			out <- js
+			return true
+		})
 		out <- nil
 	}
 }
 
-func RecursiveDescent(out chan<- *jsontree.JsonTree, js *jsontree.JsonTree) {
-	recDescent(out, js)
-	out <- nil
+// applies each selector in path to the output of the one before it.
+func Chain(path ...Selector) Selector {
+	switch len(path) {
+	case 0:
+		return Identity
+	case 1:
+		return path[0]
+	}
+	head, tail := path[0], Chain(path[1:]...)
+	return func(s *Search, js *jsontree.JsonTree, yield func(*jsontree.JsonTree) bool) bool {
+		return head(s, js, func(js *jsontree.JsonTree) bool {
+			return s.visit(js) && tail(s, js, yield)
+		})
+	}
 }
-func recDescent(out chan<- *jsontree.JsonTree, js *jsontree.JsonTree) {
-	out <- js
-	if a, err := js.Array(); err == nil {
-		for i := range a {
-			elem := js.GetIndex(i)
-			recDescent(out, elem)
-		}
-	} else if m, err := js.Object(); err == nil {
-		for k := range m {
-			val := js.Get(k)
-			recDescent(out, val)
-		}
+
+func Count(path ...Selector) Selector {
+	sel := Chain(path...)
+	return func(s *Search, js *jsontree.JsonTree, yield func(*jsontree.JsonTree) bool) bool {
+		count := 0
+		sel(s, js, func(*jsontree.JsonTree) bool {
+			count++
+			return true
+		})
+		return yield(jsontree.NewNumber(float64(count)))
 	}
 }
 
-func Identity(out chan<- *jsontree.JsonTree, js *jsontree.JsonTree) {
-	out <- js
-	out <- nil
+func RecursiveDescent(s *Search, js *jsontree.JsonTree, yield func(*jsontree.JsonTree) bool) bool {
+	return jsontree.Walk(js, func(node *jsontree.JsonTree) jsontree.WalkAction {
+		if !s.visit(node) || !yield(node) {
+			return jsontree.Stop
+		}
+		return jsontree.Continue
+	})
 }
 
-func Parent(out chan<- *jsontree.JsonTree, js *jsontree.JsonTree) {
+func Identity(s *Search, js *jsontree.JsonTree, yield func(*jsontree.JsonTree) bool) bool {
+	return yield(js)
+}
+
+func Parent(s *Search, js *jsontree.JsonTree, yield func(*jsontree.JsonTree) bool) bool {
 	jsparent := js.Parent()
 	if jsparent != nil {
-		out <- jsparent
+		return yield(jsparent)
 	}
-	out <- nil
+	return true
 }
 
-func Root(out chan<- *jsontree.JsonTree, js *jsontree.JsonTree) {
-	out <- js.Root()
-	out <- nil
+func Root(s *Search, js *jsontree.JsonTree, yield func(*jsontree.JsonTree) bool) bool {
+	return yield(js.Root())
 }
 
-func All(out chan<- *jsontree.JsonTree, js *jsontree.JsonTree) {
+// selects the elements of an array or the values of an object. object values
+// are selected in the order given by jsontree.JsonTree.Keys().
+func All(s *Search, js *jsontree.JsonTree, yield func(*jsontree.JsonTree) bool) bool {
 	if a, err := js.Array(); err == nil {
 		for i := range a {
-			out <- js.GetIndex(i)
+			if jschild := js.GetIndex(i); !s.visit(jschild) || !yield(jschild) {
+				return false
+			}
 		}
-	} else if m, err := js.Object(); err == nil {
-		for k := range m {
-			out <- js.Get(k)
+	} else if keys, err := js.Keys(); err == nil {
+		for _, k := range keys {
+			if jschild := js.Get(k); !s.visit(jschild) || !yield(jschild) {
+				return false
+			}
 		}
 	}
-	out <- nil
+	return true
 }
 
-func IgnoreErrors(out chan<- *jsontree.JsonTree, js *jsontree.JsonTree) {
+func IgnoreErrors(s *Search, js *jsontree.JsonTree, yield func(*jsontree.JsonTree) bool) bool {
 	if js.Err() == nil {
-		out <- js
+		return yield(js)
 	}
-	out <- nil
+	return true
 }
 
-func JustErrors(out chan<- *jsontree.JsonTree, js *jsontree.JsonTree) {
+func JustErrors(s *Search, js *jsontree.JsonTree, yield func(*jsontree.JsonTree) bool) bool {
 	if js.Err() != nil {
-		out <- js
+		return yield(js)
 	}
-	out <- nil
+	return true
 }
 
 func Key(key string) Selector {
-	return func(out chan<- *jsontree.JsonTree, js *jsontree.JsonTree) {
+	return func(s *Search, js *jsontree.JsonTree, yield func(*jsontree.JsonTree) bool) bool {
 		jschild := js.Get(key)
 		err := js.Err()
 		if err == nil {
-			out <- jschild
+			return yield(jschild)
 		}
-		out <- nil
+		return true
 	}
 }
 
+// selects the i-th element of an array. negative indices count back from the
+// end of the array, so Index(-1) selects the last element.
 func Index(i int) Selector {
-	return func(out chan<- *jsontree.JsonTree, js *jsontree.JsonTree) {
+	return func(s *Search, js *jsontree.JsonTree, yield func(*jsontree.JsonTree) bool) bool {
 		a, _ := js.Array()
-		if 0 < i && i < len(a) {
-			out <- js.GetIndex(i)
+		j := i
+		if j < 0 {
+			j += len(a)
 		}
-		out <- nil
+		if 0 <= j && j < len(a) {
+			return yield(js.GetIndex(j))
+		}
+		return true
 	}
 }
 
-func Has(sel ...Selector) Selector {
-	return func(out chan<- *jsontree.JsonTree, js *jsontree.JsonTree) {
-		if len(Lookup(js, sel...)) > 0 {
-			out <- js
+// an omitted Slice bound.
+const Unbounded = math.MinInt
+
+// selects the elements of an array from start up to but not including end,
+// taking every step-th element. negative bounds count back from the end of
+// the array and a negative step selects elements in reverse order. an
+// Unbounded start or end extends to the end of the array in the direction of
+// step. selects nothing if step is zero. see RFC 9535, section 2.3.4.
+func Slice(start, end, step int) Selector {
+	return func(s *Search, js *jsontree.JsonTree, yield func(*jsontree.JsonTree) bool) bool {
+		a, _ := js.Array()
+		lower, upper := sliceBounds(start, end, step, len(a))
+		switch {
+		case step > 0:
+			for i := lower; i < upper; i += step {
+				if !yield(js.GetIndex(i)) {
+					return false
+				}
+			}
+		case step < 0:
+			for i := upper; lower < i; i += step {
+				if !yield(js.GetIndex(i)) {
+					return false
+				}
+			}
 		}
-		out <- nil
+		return true
 	}
 }
 
-func EqualString(x string, sel ...Selector) Selector {
-	return func(out chan<- *jsontree.JsonTree, js *jsontree.JsonTree) {
-		for _, jschild := range Lookup(js, sel...) {
-			str, err := jschild.String()
-			if err == nil && str == x {
-				out <- js
-				break
-			}
+func sliceBounds(start, end, step, n int) (lower, upper int) {
+	normalize := func(i int) int {
+		if i < 0 {
+			return n + i
 		}
-		out <- nil
+		return i
+	}
+	clamp := func(i, min, max int) int {
+		if i < min {
+			return min
+		}
+		if i > max {
+			return max
+		}
+		return i
 	}
+	if step >= 0 {
+		if start == Unbounded {
+			start = 0
+		}
+		if end == Unbounded {
+			end = n
+		}
+		lower = clamp(normalize(start), 0, n)
+		upper = clamp(normalize(end), 0, n)
+		return lower, upper
+	}
+	if start == Unbounded {
+		start = n - 1
+	}
+	if end == Unbounded {
+		return -1, clamp(normalize(start), -1, n-1)
+	}
+	upper = clamp(normalize(start), -1, n-1)
+	lower = clamp(normalize(end), -1, n-1)
+	return lower, upper
 }
 
-func EqualFloat64(x float64, sel ...Selector) Selector {
-	return func(out chan<- *jsontree.JsonTree, js *jsontree.JsonTree) {
-		for _, jschild := range Lookup(js, sel...) {
-			f, err := jschild.Number()
-			if err == nil && f == x {
-				out <- js
-				break
+// selects the results of each selector in the order the selectors are given.
+func Union(sel ...Selector) Selector {
+	return func(s *Search, js *jsontree.JsonTree, yield func(*jsontree.JsonTree) bool) bool {
+		for i := range sel {
+			if !sel[i](s, js, yield) {
+				return false
 			}
 		}
-		out <- nil
+		return true
 	}
 }
 
-func EqualBool(x bool, sel ...Selector) Selector {
-	return func(out chan<- *jsontree.JsonTree, js *jsontree.JsonTree) {
-		for _, jschild := range Lookup(js, sel...) {
-			b, err := jschild.Boolean()
-			if err == nil && b == x {
-				out <- js
-				break
-			}
+func Has(sel ...Selector) Selector {
+	return func(s *Search, js *jsontree.JsonTree, yield func(*jsontree.JsonTree) bool) bool {
+		if s.first(js, sel) != nil {
+			return yield(js)
 		}
-		out <- nil
+		return true
+	}
+}
+
+// selects js if path selects any tree for which match returns true.
+func anyMatch(match func(*jsontree.JsonTree) bool, path []Selector) Selector {
+	return func(s *Search, js *jsontree.JsonTree, yield func(*jsontree.JsonTree) bool) bool {
+		found := false
+		if len(path) > 0 {
+			Chain(path...)(s, js, func(jschild *jsontree.JsonTree) bool {
+				found = match(jschild)
+				return !found
+			})
+		}
+		if found {
+			return yield(js)
+		}
+		return true
 	}
 }
+
+func EqualString(x string, sel ...Selector) Selector {
+	return anyMatch(func(jschild *jsontree.JsonTree) bool {
+		str, err := jschild.String()
+		return err == nil && str == x
+	}, sel)
+}
+
+func EqualFloat64(x float64, sel ...Selector) Selector {
+	return anyMatch(func(jschild *jsontree.JsonTree) bool {
+		f, err := jschild.Number()
+		return err == nil && f == x
+	}, sel)
+}
+
+func EqualBool(x bool, sel ...Selector) Selector {
+	return anyMatch(func(jschild *jsontree.JsonTree) bool {
+		b, err := jschild.Boolean()
+		return err == nil && b == x
+	}, sel)
+}
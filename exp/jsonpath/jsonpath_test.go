@@ -11,24 +11,25 @@ import (
 	y "github.com/bmatsuo/yup"
 	yt "github.com/bmatsuo/yup/yuptype"
 
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
 	"testing"
 )
 
 type TestError string
 
 func testSel(t *testing.T, sel Selector, jsstr string, val ...interface{}) {
-	out := make(chan *jsontree.JsonTree, 2)
 	_js := jsontree.New()
 	err := _js.UnmarshalJSON([]byte(jsstr))
 	if err != nil {
 		yt.Nil(t, err)
 	}
-	go sel(out, _js)
 	i := 0
-	for js := range out {
-		if js == nil {
-			break
-		}
+	for _, js := range Lookup(_js, sel) {
 		if i < len(val) {
 			if err := js.Err(); err != nil {
 				_, ok := val[i].(TestError)
@@ -150,3 +151,369 @@ func TestParse(t *testing.T) {
 	//testSel(t, sel, `{"test":{"foo1":{"bar":{"qux":true}}, "foo2":{"bar":{"qux":true}}},"bar":{"qux":true}}`,
 	//	true, true, true)
 }
+
+func TestParseBracket(t *testing.T) {
+	sel, err := Parse("$[0]")
+	yt.Nil(t, err)
+	testSel(t, sel, `["a", "b"]`, "a")
+	testSel(t, sel, `[]`)
+
+	sel, err = Parse("$.items[1].name")
+	yt.Nil(t, err)
+	testSel(t, sel, `{"items":[{"name":"a"},{"name":"b"}]}`, "b")
+
+	sel, err = Parse("$['quoted key']")
+	yt.Nil(t, err)
+	testSel(t, sel, `{"quoted key":1}`, float64(1))
+
+	sel, err = Parse(`$["key with spaces"]["it's"]`)
+	yt.Nil(t, err)
+	testSel(t, sel, `{"key with spaces":{"it's":true}}`, true)
+
+	sel, err = Parse(`$['it\'s']`)
+	yt.Nil(t, err)
+	testSel(t, sel, `{"it's":"escaped"}`, "escaped")
+
+	sel, err = Parse("$[*]")
+	yt.Nil(t, err)
+	testSel(t, sel, `[1, 2]`, float64(1), float64(2))
+
+	sel, err = Parse("$")
+	yt.Nil(t, err)
+	testSel(t, sel, `"root"`, "root")
+
+	for _, bad := range []string{"$[", "$[0", "$['open]", "$[1.5]", "$[]", "$.a]"} {
+		_, err = Parse(bad)
+		y.Assert(t, err != nil, "expected error for ", bad)
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	books := `{"limit":10,"books":[
+		{"title":"a","price":8,"tags":["x"]},
+		{"title":"b","price":12,"used":true},
+		{"title":"c","price":10,"used":false,"note":null}
+	]}`
+
+	sel, err := Parse("$.books[?(@.price < 10)].title")
+	yt.Nil(t, err)
+	testSel(t, sel, books, "a")
+
+	sel, err = Parse("$.books[?(@.price >= $.limit)].title")
+	yt.Nil(t, err)
+	testSel(t, sel, books, "b", "c")
+
+	sel, err = Parse("$.books[?@.used == true].title")
+	yt.Nil(t, err)
+	testSel(t, sel, books, "b")
+
+	sel, err = Parse("$.books[?(@.note == null)].title")
+	yt.Nil(t, err)
+	testSel(t, sel, books, "c")
+
+	sel, err = Parse(`$.books[?(@.title != "a" && !@.note)].title`)
+	yt.Nil(t, err)
+	testSel(t, sel, books, "b")
+
+	sel, err = Parse(`$.books[?(@.tags || (@.price > 11 && @.title == 'b'))].title`)
+	yt.Nil(t, err)
+	testSel(t, sel, books, "a", "b")
+
+	sel, err = Parse(`$.books[?(@.title <= 'b')].price`)
+	yt.Nil(t, err)
+	testSel(t, sel, books, float64(8), float64(12))
+
+	sel, err = Parse(`$[?(@ > -1 && @ != 2)]`)
+	yt.Nil(t, err)
+	testSel(t, sel, `[-2, 0, 2, "3"]`, float64(0))
+
+	for _, bad := range []string{"$[?]", "$[?(@.a]", "$[?(1)]", "$[?(@.a ==)]", "$[?(@.a & @.b)]"} {
+		_, err = Parse(bad)
+		y.Assert(t, err != nil, "expected error for ", bad)
+	}
+}
+
+func TestSlice(t *testing.T) {
+	a := `[0, 1, 2, 3, 4, 5]`
+	testSel(t, Slice(1, 3, 1), a, float64(1), float64(2))
+	testSel(t, Slice(-2, Unbounded, 1), a, float64(4), float64(5))
+	testSel(t, Slice(Unbounded, Unbounded, 2), a, float64(0), float64(2), float64(4))
+	testSel(t, Slice(Unbounded, Unbounded, -2), a, float64(5), float64(3), float64(1))
+	testSel(t, Slice(4, 1, -1), a, float64(4), float64(3), float64(2))
+	testSel(t, Slice(-100, 100, 1), `[0, 1]`, float64(0), float64(1))
+	testSel(t, Slice(0, 6, 0), a)
+	testSel(t, Slice(0, 2, 1), `{"a":1}`)
+	testSel(t, Index(-1), a, float64(5))
+	testSel(t, Index(-7), a)
+}
+
+func TestParseSlice(t *testing.T) {
+	a := `[0, 1, 2, 3, 4]`
+	for path, vals := range map[string][]interface{}{
+		"$[0]":      {float64(0)},
+		"$[-1]":     {float64(4)},
+		"$[1:3]":    {float64(1), float64(2)},
+		"$[-3:]":    {float64(2), float64(3), float64(4)},
+		"$[:2]":     {float64(0), float64(1)},
+		"$[::2]":    {float64(0), float64(2), float64(4)},
+		"$[::-1]":   {float64(4), float64(3), float64(2), float64(1), float64(0)},
+		"$[3:1:-1]": {float64(3), float64(2)},
+		"$[:]":      {float64(0), float64(1), float64(2), float64(3), float64(4)},
+	} {
+		sel, err := Parse(path)
+		yt.Nil(t, err, path)
+		if err == nil {
+			testSel(t, sel, a, vals...)
+		}
+	}
+	for _, bad := range []string{"$[1:2:3:4]", "$[1 2]", "$[1:-]"} {
+		_, err := Parse(bad)
+		y.Assert(t, err != nil, "expected error for ", bad)
+	}
+}
+
+func TestUnion(t *testing.T) {
+	sel := Union(Key("b"), Key("a"))
+	testSel(t, sel, `{"a":1, "b":2}`, float64(2), float64(1))
+
+	sel, err := Parse("$.user['name','id']")
+	yt.Nil(t, err)
+	testSel(t, sel, `{"user":{"id":1, "email":"e", "name":"n"}}`, "n", float64(1))
+
+	sel, err = Parse("$.rows[2, 0, -1]")
+	yt.Nil(t, err)
+	testSel(t, sel, `{"rows":["a", "b", "c", "d"]}`, "c", "a", "d")
+
+	sel, err = Parse("$[0:2, ?(@ == 'd')]")
+	yt.Nil(t, err)
+	testSel(t, sel, `["a", "b", "c", "d"]`, "a", "b", "d")
+
+	for _, bad := range []string{"$[0,]", "$[,0]", "$['a' 'b']"} {
+		_, err = Parse(bad)
+		y.Assert(t, err != nil, "expected error for ", bad)
+	}
+}
+
+func TestFirst(t *testing.T) {
+	js := jsontree.New()
+	err := js.UnmarshalJSON([]byte(`[[1, 2], [3, 4]]`))
+	yt.Nil(t, err)
+	visited := 0
+	counting := func(s *Search, js *jsontree.JsonTree, yield func(*jsontree.JsonTree) bool) bool {
+		visited++
+		return yield(js)
+	}
+	first := First(js, RecursiveDescent, counting, IgnoreErrors)
+	v, _ := first.Interface()
+	yt.Equal(t, []interface{}{[]interface{}{float64(1), float64(2)}, []interface{}{float64(3), float64(4)}}, v)
+	yt.Equal(t, 1, visited)
+
+	n := 0
+	for js := range Select(js, All, All) {
+		n++
+		if x, _ := js.Number(); x == 2 {
+			break
+		}
+	}
+	yt.Equal(t, 2, n)
+	y.Assert(t, First(js, Key("nope"), IgnoreErrors) == nil, "expected nil")
+}
+
+func TestChanAdapters(t *testing.T) {
+	legacy := func(out chan<- *jsontree.JsonTree, js *jsontree.JsonTree) {
+		for i := 0; i < 3; i++ {
+			out <- jsontree.NewNumber(float64(i))
+		}
+		out <- nil
+	}
+	sel := FromChan(legacy)
+	testSel(t, sel, `null`, float64(0), float64(1), float64(2))
+	js := First(jsontree.NewNull(), sel)
+	x, _ := js.Number()
+	yt.Equal(t, float64(0), x)
+
+	out := make(chan *jsontree.JsonTree, 4)
+	ToChan(Index(1))(out, jsontree.NewArray([]interface{}{"a", "b"}))
+	str, _ := (<-out).String()
+	yt.Equal(t, "b", str)
+	y.Assert(t, <-out == nil, "expected nil terminator")
+}
+
+func TestLookupContext(t *testing.T) {
+	js := jsontree.New()
+	err := js.UnmarshalJSON([]byte(`{"a":[{"b":[1, 2, 3]}, {"b":[4]}]}`))
+	yt.Nil(t, err)
+	path, err := ParsePath("$.**.b.*")
+	yt.Nil(t, err)
+
+	bg := context.Background()
+	results, err := LookupContext(bg, js, Limits{}, path...)
+	yt.Nil(t, err)
+	yt.Equal(t, 4, len(results))
+
+	results, err = LookupContext(bg, js, Limits{MaxResults: 2}, path...)
+	yt.Equal(t, &LimitError{"results", 2}, err)
+	yt.Equal(t, 2, len(results))
+
+	_, err = LookupContext(bg, js, Limits{MaxNodes: 5}, path...)
+	yt.Equal(t, &LimitError{"nodes", 5}, err)
+
+	_, err = LookupContext(bg, js, Limits{MaxDepth: 3}, path...)
+	yt.Equal(t, &LimitError{"depth", 3}, err)
+
+	results, err = LookupContext(bg, js, Limits{MaxDepth: 5, MaxNodes: 100, MaxResults: 4}, path...)
+	yt.Nil(t, err)
+	yt.Equal(t, 4, len(results))
+
+	ctx, cancel := context.WithCancel(bg)
+	cancel()
+	_, err = LookupContext(ctx, js, Limits{}, path...)
+	yt.Equal(t, context.Canceled, err)
+
+	ctx, cancel = context.WithCancel(bg)
+	stop := func(s *Search, js *jsontree.JsonTree, yield func(*jsontree.JsonTree) bool) bool {
+		cancel()
+		return yield(js)
+	}
+	results, err = LookupContext(ctx, js, Limits{}, All, stop, All)
+	yt.Equal(t, context.Canceled, err)
+	yt.Equal(t, 0, len(results))
+
+	var n int
+	RecursiveDescent(nil, js, func(*jsontree.JsonTree) bool {
+		n++
+		return true
+	})
+	yt.Equal(t, 10, n)
+
+	// limits apply within a single compiled selector
+	deep := jsontree.New()
+	err = deep.UnmarshalJSON([]byte(strings.Repeat(`{"a":[`, 100) + strings.Repeat(`]}`, 100)))
+	yt.Nil(t, err)
+	descent, err := Parse("$.**.x")
+	yt.Nil(t, err)
+	_, err = LookupContext(bg, deep, Limits{}, descent)
+	yt.Nil(t, err)
+
+	_, err = LookupContext(bg, deep, Limits{MaxDepth: 10}, descent)
+	yt.Equal(t, &LimitError{"depth", 10}, err)
+
+	_, err = LookupContext(bg, deep, Limits{MaxNodes: 50}, descent)
+	yt.Equal(t, &LimitError{"nodes", 50}, err)
+
+	_, err = LookupContext(bg, deep, Limits{MaxNodes: 50}, Count(RecursiveDescent))
+	yt.Equal(t, &LimitError{"nodes", 50}, err)
+
+	filter, err := Parse("$.**[?(@.x == 1)]")
+	yt.Nil(t, err)
+	_, err = LookupContext(bg, deep, Limits{MaxNodes: 50}, filter)
+	yt.Equal(t, &LimitError{"nodes", 50}, err)
+
+	ctx, cancel = context.WithCancel(bg)
+	defer cancel()
+	_, err = LookupContext(ctx, deep, Limits{}, stop, descent)
+	yt.Equal(t, context.Canceled, err)
+}
+
+func TestFilterUseNumber(t *testing.T) {
+	js := jsontree.New()
+	js.UseNumber()
+	err := js.UnmarshalJSON([]byte(`[{"id":1,"n":"a"},{"id":2,"n":"b"}]`))
+	yt.Nil(t, err)
+	sel, err := Parse("$[?(@.id == 2)].n")
+	yt.Nil(t, err)
+	results := Lookup(js, sel)
+	yt.Equal(t, 1, len(results))
+	if len(results) == 1 {
+		str, _ := results[0].String()
+		yt.Equal(t, "b", str)
+	}
+}
+
+func TestFilterNumberExact(t *testing.T) {
+	js := jsontree.New()
+	js.UseNumber()
+	err := js.UnmarshalJSON([]byte(`[{"id":9007199254740993},{"id":9007199254740992}]`))
+	yt.Nil(t, err)
+	sel, err := Parse("$[?(@.id == 9007199254740992)]")
+	yt.Nil(t, err)
+	results := Lookup(js, sel)
+	yt.Equal(t, 1, len(results))
+	if len(results) == 1 {
+		id, _ := results[0].Get("id").NumberString()
+		yt.Equal(t, "9007199254740992", id)
+	}
+
+	sel, err = Parse("$[?(@.id > 9007199254740992)]")
+	yt.Nil(t, err)
+	yt.Equal(t, 1, len(Lookup(js, sel)))
+}
+
+func TestStream(t *testing.T) {
+	input := `{"items":[{"id":1,"tags":["a"]},{"id":2,"tags":[]},{"id":3}],"name":"x"}
+	{"items":{"id":4}}
+	[1]`
+	for _, path := range []string{
+		`$`, `$.items`, `$.items[*].id`, `$.items[1:].id`, `$.items[0]`,
+		`$.items[::2].id`, `$.items.*.tags`, `$.name`, `$['items'][0]['tags'][0]`,
+		`$.items[5]`, `$.missing`, `$.items.id`, `$.name.x`,
+	} {
+		sp, err := CompileStream(path)
+		yt.Nil(t, err)
+		sp.Ordered = true
+		sel, err := Parse(path)
+		yt.Nil(t, err)
+
+		dec := json.NewDecoder(strings.NewReader(input))
+		docs := json.NewDecoder(strings.NewReader(input))
+		for {
+			var streamed []string
+			err := sp.Select(dec, func(r StreamResult) error {
+				if r.Err != nil {
+					streamed = append(streamed, r.Err.Error())
+				} else {
+					p, _ := json.Marshal(r.Value)
+					streamed = append(streamed, string(p))
+				}
+				return nil
+			})
+			js := jsontree.NewOrdered()
+			docerr := docs.Decode(js)
+			if err == io.EOF {
+				yt.Equal(t, io.EOF, docerr)
+				break
+			}
+			yt.Nil(t, err)
+			var looked []string
+			for _, js := range Lookup(js, sel) {
+				if err := js.Err(); err != nil {
+					looked = append(looked, err.Error())
+				} else {
+					p, _ := json.Marshal(js)
+					looked = append(looked, string(p))
+				}
+			}
+			y.Assert(t, reflect.DeepEqual(looked, streamed), fmt.Sprintf("%s: stream %q != lookup %q", path, streamed, looked))
+		}
+	}
+
+	for _, path := range []string{`$..id`, `$.**`, `$.items[-1]`, `$.items[?(@.id)]`, `$['a','b']`, `$[::-1]`} {
+		_, err := CompileStream(path)
+		yt.Equal(t, ErrNotStreamable, err)
+	}
+
+	sp, err := CompileStream(`$.a[1]`)
+	yt.Nil(t, err)
+	var offset int64
+	var path jsontree.Path
+	err = sp.Select(json.NewDecoder(strings.NewReader(` {"a": [0, {"b": 1}]}`)), func(r StreamResult) error {
+		offset, path = r.Offset, r.Path
+		return nil
+	})
+	yt.Nil(t, err)
+	yt.Equal(t, int64(11), offset)
+	yt.Equal(t, `$['a'][1]`, path.String())
+
+	err = sp.Select(json.NewDecoder(strings.NewReader(`{"a": [0`)), func(StreamResult) error { return nil })
+	y.Assert(t, err != nil && err != io.EOF, "truncated input")
+}
@@ -38,8 +38,20 @@ const (
 	ItemLessEqual
 	ItemNotEqual
 	ItemString
+	ItemQuestion
+	ItemLeftParen
+	ItemRightParen
+	ItemAt
+	ItemAnd
+	ItemOr
+	ItemNot
+	ItemColon
+	ItemComma
 )
 
+// an item emitted by the lexer.
+type Item = lexer.Item
+
 type Interface interface {
 	Next() *lexer.Item
 }
@@ -73,6 +85,7 @@ func Start(lex *lexer.Lexer) lexer.StateFn {
 	case 1:
 		debugln("FOUND STAR")
 		lex.Emit(ItemStar)
+		return Start
 	case 2:
 		debugln("FOUND STAR STAR")
 		lex.Emit(ItemStarStar)
@@ -83,10 +96,12 @@ func Start(lex *lexer.Lexer) lexer.StateFn {
 	switch r, _ := lex.Peek(); {
 	case r == lexer.EOF:
 		return nil
-	case unicode.IsLetter(r):
+	case unicode.IsLetter(r), r == '_':
 		return PathKey
-	case unicode.IsDigit(r):
+	case unicode.IsDigit(r), r == '-':
 		return Number
+	case r == '\'' || r == '"':
+		return String
 	case r == '[':
 		debugln("FOUND LEFT BRACKET")
 		lex.Advance()
@@ -99,6 +114,48 @@ func Start(lex *lexer.Lexer) lexer.StateFn {
 		debugln("FOUND DOLLAR")
 		lex.Advance()
 		lex.Emit(ItemDollar)
+	case r == '@':
+		debugln("FOUND AT")
+		lex.Advance()
+		lex.Emit(ItemAt)
+	case r == ':':
+		debugln("FOUND COLON")
+		lex.Advance()
+		lex.Emit(ItemColon)
+	case r == ',':
+		debugln("FOUND COMMA")
+		lex.Advance()
+		lex.Emit(ItemComma)
+	case r == '?':
+		debugln("FOUND QUESTION")
+		lex.Advance()
+		lex.Emit(ItemQuestion)
+	case r == '(':
+		debugln("FOUND LEFT PAREN")
+		lex.Advance()
+		lex.Emit(ItemLeftParen)
+	case r == ')':
+		debugln("FOUND RIGHT PAREN")
+		lex.Advance()
+		lex.Emit(ItemRightParen)
+	case r == '&':
+		lex.Advance()
+		if r, _ := lex.Peek(); r == '&' {
+			debugln("FOUND AND")
+			lex.Advance()
+			lex.Emit(ItemAnd)
+		} else {
+			return lex.Errorf("expected '&' got %c", r)
+		}
+	case r == '|':
+		lex.Advance()
+		if r, _ := lex.Peek(); r == '|' {
+			debugln("FOUND OR")
+			lex.Advance()
+			lex.Emit(ItemOr)
+		} else {
+			return lex.Errorf("expected '|' got %c", r)
+		}
 	case r == '>':
 		lex.Advance()
 		if r, _ := lex.Peek(); r == '=' {
@@ -122,6 +179,7 @@ func Start(lex *lexer.Lexer) lexer.StateFn {
 	case r == '=':
 		debugln("FOUND EQUAL")
 		lex.Advance()
+		lex.Accept("=")
 		lex.Emit(ItemEqual)
 	case r == '!':
 		lex.Advance()
@@ -130,8 +188,11 @@ func Start(lex *lexer.Lexer) lexer.StateFn {
 			lex.Advance()
 			lex.Emit(ItemNotEqual)
 		} else {
-			return lex.Errorf("expected '=' got %c", r)
+			debugln("FOUND NOT")
+			lex.Emit(ItemNot)
 		}
+	default:
+		return lex.Errorf("unexpected %c", r)
 	}
 	return Start
 }
@@ -167,10 +228,11 @@ func PathKey(lex *lexer.Lexer) lexer.StateFn {
 }
 
 func Number(lex *lexer.Lexer) lexer.StateFn {
+	lex.Accept("-")
 	if lex.AcceptRunRange(unicode.Digit) == 0 {
 		r, _ := lex.Peek()
 		if r == lexer.EOF {
-			return nil
+			return lex.Errorf("expected digit got EOF")
 		}
 		return lex.Errorf("expected digit got %c", r)
 	}
@@ -179,13 +241,33 @@ func Number(lex *lexer.Lexer) lexer.StateFn {
 		lex.AcceptRunRange(unicode.Digit)
 	}
 	if lex.Accept("eE") {
-		lex.Accept("-")
+		lex.Accept("+-")
 		lex.AcceptRunRange(unicode.Digit)
 	}
 	lex.Emit(ItemNumber)
 	return Start
 }
 
+// a single or double quoted string. the emitted item includes the quotes and
+// any escape sequences.
+func String(lex *lexer.Lexer) lexer.StateFn {
+	quote, _ := lex.Advance()
+	for {
+		switch r, _ := lex.Advance(); r {
+		case lexer.EOF:
+			return lex.Errorf("unterminated string")
+		case '\\':
+			if r, _ := lex.Advance(); r == lexer.EOF {
+				return lex.Errorf("unterminated string")
+			}
+		case quote:
+			debugln("FOUND STRING")
+			lex.Emit(ItemString)
+			return Start
+		}
+	}
+}
+
 func Bracket(lex *lexer.Lexer) lexer.StateFn {
 	switch r, _ := lex.Peek(); {
 	case r == lexer.EOF:
@@ -0,0 +1,111 @@
+// Copyright 2013, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// limits.go [created: Sat, 17 Oct 2026]
+
+package jsonpath
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bmatsuo/go-jsontree"
+)
+
+// bounds on the work done by LookupContext. a zero field means no limit.
+type Limits struct {
+	// the maximum number of trees selected.
+	MaxResults int
+	// the maximum number of trees visited. a tree is counted each time it is
+	// passed between selectors, including the selectors of filters, and each
+	// time it is visited by RecursiveDescent or All.
+	MaxNodes int
+	// the maximum depth, relative to the root, of any tree visited.
+	MaxDepth int
+}
+
+// returned by LookupContext when a limit is exceeded.
+type LimitError struct {
+	Limit string // "results", "nodes" or "depth"
+	Max   int
+}
+
+func (err *LimitError) Error() string {
+	return fmt.Sprintf("jsonpath: %s limit exceeded (%d)", err.Limit, err.Max)
+}
+
+// like Lookup but stops when ctx is done or a limit in limits is exceeded.
+// the trees selected before stopping are returned along with ctx.Err() or a
+// *LimitError.
+//
+// ctx and the limits are checked each time a tree is passed from one selector
+// to the next, including the selectors of filters and of paths compiled with
+// Parse, so a single selector such as one for "$..x" is bounded as well.
+func LookupContext(ctx context.Context, js *jsontree.JsonTree, limits Limits, path ...Selector) ([]*jsontree.JsonTree, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(path) == 0 {
+		return nil, nil
+	}
+	s := &Search{ctx: ctx, limits: limits}
+	var selected []*jsontree.JsonTree
+	Chain(path...)(s, js, func(js *jsontree.JsonTree) bool {
+		if !s.visit(js) {
+			return false
+		}
+		if max := limits.MaxResults; max > 0 && len(selected) >= max {
+			s.err = &LimitError{"results", max}
+			return false
+		}
+		selected = append(selected, js)
+		return true
+	})
+	return selected, s.err
+}
+
+// the state of a lookup. Lookup, First and Select use a Search without
+// limits, LookupContext one that checks its context and limits. a nil *Search
+// has no limits, so a Selector may be called directly with nil.
+type Search struct {
+	ctx    context.Context
+	limits Limits
+	nodes  int
+	err    error
+}
+
+// returns false and sets s.err if visiting js exceeds a limit.
+func (s *Search) visit(js *jsontree.JsonTree) bool {
+	if s == nil || s.ctx == nil {
+		return true
+	}
+	if s.err != nil {
+		return false
+	}
+	select {
+	case <-s.ctx.Done():
+		s.err = s.ctx.Err()
+		return false
+	default:
+	}
+	s.nodes++
+	if max := s.limits.MaxNodes; max > 0 && s.nodes > max {
+		s.err = &LimitError{"nodes", max}
+		return false
+	}
+	if max := s.limits.MaxDepth; max > 0 && depth(js, max) > max {
+		s.err = &LimitError{"depth", max}
+		return false
+	}
+	return true
+}
+
+// the number of ancestors of js, counting no further than max+1.
+func depth(js *jsontree.JsonTree, max int) int {
+	n := 0
+	for js = js.Parent(); js != nil && n <= max; js = js.Parent() {
+		n++
+	}
+	return n
+}
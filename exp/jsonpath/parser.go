@@ -10,7 +10,9 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strconv"
 
+	"github.com/bmatsuo/go-jsontree"
 	"github.com/bmatsuo/go-jsontree/exp/jsonpath/lexer"
 )
 
@@ -35,62 +37,457 @@ func debugf(format string, v ...interface{}) {
 }
 
 func Parse(input string) (Selector, error) {
+	selectors, err := ParsePath(input)
+	if err != nil {
+		return nil, err
+	}
+	return Chain(selectors...), nil
+}
+
+// like Parse but returns the steps of the path as separate selectors instead
+// of chaining them together.
+func ParsePath(input string) ([]Selector, error) {
+	_, selectors, err := parse(input)
+	return selectors, err
+}
+
+// parses input, returning the parser so the steps it recorded can be used.
+func parse(input string) (*parser, []Selector, error) {
+	p := newParser(input)
+	selectors, err := p.parsePath()
+	if err != nil {
+		return nil, nil, err
+	}
+	switch item := p.next(); item.Type {
+	case lexer.ItemEOF:
+		debug("EOF\n")
+	case lexer.ItemError:
+		return nil, nil, errors.New(item.Value)
+	default:
+		return nil, nil, fmt.Errorf("unexpected %q", item.Value)
+	}
+	debugf("%d selectors\n", len(selectors))
+	if len(selectors) == 0 {
+		return nil, nil, fmt.Errorf("empty")
+	}
+	return p, selectors, nil
+}
+
+type parser struct {
+	lex    lexer.Interface
+	peeked *lexer.Item
+
+	// the steps of the path for evaluation on a stream. they are only
+	// meaningful if unstreamable is false.
+	steps        []step
+	unstreamable bool
+}
+
+func newParser(input string) *parser {
+	return &parser{lex: lexer.New(input)}
+}
+
+// the next item without consuming it.
+func (p *parser) peek() *lexer.Item {
+	if p.peeked == nil {
+		p.peeked = p.lex.Next()
+	}
+	return p.peeked
+}
+
+func (p *parser) next() *lexer.Item {
+	item := p.peek()
+	p.peeked = nil
+	return item
+}
+
+// parses a path with an optional leading "$" or "@" until an item is found
+// that can not continue the path. the item is not consumed.
+func (p *parser) parsePath() ([]Selector, error) {
+	dollar := false
+	switch p.peek().Type {
+	case lexer.ItemDollar:
+		debug("DOLLAR\n")
+		p.next()
+		dollar = true
+	case lexer.ItemAt:
+		debug("AT\n")
+		p.next()
+		dollar = true
+	}
+	selectors, err := p.parseSegments()
+	if err != nil {
+		return nil, err
+	}
+	if dollar && len(selectors) == 0 {
+		selectors = append(selectors, Identity)
+	}
+	return selectors, nil
+}
+
+// parses dot and bracket segments until an item is found that can not
+// continue the path. the item is not consumed.
+func (p *parser) parseSegments() ([]Selector, error) {
 	selectors := make([]Selector, 0, 1)
-	lex := lexer.New(input)
 	for {
-		switch item := lex.Next(); item.Type {
-		case lexer.ItemEOF:
-			debug("EOF\n")
-			debugf("%d selectors\n", len(selectors))
-			switch len(selectors) {
-			case 0:
-				return nil, fmt.Errorf("empty")
-			case 1:
-				return selectors[0], nil
-			default:
-				return Chain(selectors...), nil
-			}
+		switch item := p.peek(); item.Type {
 		case lexer.ItemError:
 			debug("ERROR\n")
 			return nil, errors.New(item.Value)
-		case lexer.ItemDollar:
-			debug("DOLLAR ")
-			next := lex.Next()
-			if next.Type != lexer.ItemDot {
-				return nil, fmt.Errorf("expected \".\" but got %q", next.Value)
-			}
-			fallthrough
 		case lexer.ItemDotDot:
 			debug("DOTDOT ")
+			p.unstreamable = true
 			fallthrough // FIXME
 		case lexer.ItemDot:
 			debug("DOT\n")
-			switch next := lex.Next(); next.Type {
+			p.next()
+			switch next := p.next(); next.Type {
 			case lexer.ItemEOF:
 				return nil, errors.New("unexpected EOF")
+			case lexer.ItemError:
+				return nil, errors.New(next.Value)
 			case lexer.ItemStarStar:
 				debug("STAR STAR\n")
+				p.unstreamable = true
 				selectors = append(selectors, RecursiveDescent)
 			case lexer.ItemStar:
 				debug("STAR\n")
+				p.steps = append(p.steps, step{all: true})
 				selectors = append(selectors, All)
 			case lexer.ItemPathKey:
-				debug("PATH KEY %s\n", next.Value)
+				debugf("PATH KEY %s\n", next.Value)
+				p.steps = append(p.steps, step{isKey: true, key: next.Value})
 				selectors = append(selectors, Key(next.Value))
 			default:
 				return nil, fmt.Errorf("expected key but got %q", next.Value)
 			}
 		case lexer.ItemLeftBracket:
 			debug("LEFTBRACKET\n")
-			sel, err := parseBracket(lex)
+			p.next()
+			sel, err := p.parseBracket()
 			if err != nil {
 				return nil, err
 			}
 			selectors = append(selectors, sel)
+		default:
+			return selectors, nil
+		}
+	}
+}
+
+// parses the contents of a bracket following the left bracket. multiple
+// selectors separated by commas form a Union.
+func (p *parser) parseBracket() (Selector, error) {
+	var union []Selector
+	for {
+		sel, err := p.parseBracketSelector()
+		if err != nil {
+			return nil, err
+		}
+		union = append(union, sel)
+		switch item := p.next(); item.Type {
+		case lexer.ItemComma:
+			debug("COMMA\n")
+			continue
+		case lexer.ItemRightBracket:
+			debug("RIGHTBRACKET\n")
+			if len(union) == 1 {
+				return union[0], nil
+			}
+			p.unstreamable = true
+			return Union(union...), nil
+		case lexer.ItemError:
+			return nil, errors.New(item.Value)
+		default:
+			return nil, fmt.Errorf("expected \"]\" but got %q", item.Value)
+		}
+	}
+}
+
+// parses a single selector inside brackets.
+func (p *parser) parseBracketSelector() (Selector, error) {
+	switch item := p.next(); item.Type {
+	case lexer.ItemError:
+		return nil, errors.New(item.Value)
+	case lexer.ItemStar:
+		debug("STAR\n")
+		p.steps = append(p.steps, step{all: true})
+		return All, nil
+	case lexer.ItemNumber, lexer.ItemColon:
+		return p.parseIndex(item)
+	case lexer.ItemString:
+		debugf("STRING %s\n", item.Value)
+		key, err := unquote(item.Value)
+		if err != nil {
+			return nil, err
+		}
+		p.steps = append(p.steps, step{isKey: true, key: key})
+		return Key(key), nil
+	case lexer.ItemQuestion:
+		debug("QUESTION\n")
+		p.unstreamable = true
+		pred, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return Filter(pred), nil
+	default:
+		return nil, fmt.Errorf("unexpected %q in brackets", item.Value)
+	}
+}
+
+// parses an index or a slice of the form start:end:step beginning with item.
+func (p *parser) parseIndex(item *lexer.Item) (Selector, error) {
+	bounds := []int{Unbounded, Unbounded, 1}
+	n := 0
+	set := false
+	for {
+		switch item.Type {
+		case lexer.ItemNumber:
+			debugf("NUMBER %s\n", item.Value)
+			if set {
+				return nil, fmt.Errorf("unexpected %q", item.Value)
+			}
+			i, err := strconv.Atoi(item.Value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid index %q", item.Value)
+			}
+			bounds[n] = i
+			set = true
+		case lexer.ItemColon:
+			debug("COLON\n")
+			n++
+			if n >= len(bounds) {
+				return nil, fmt.Errorf("too many \":\" in slice")
+			}
+			set = false
 		}
+		if t := p.peek().Type; t != lexer.ItemNumber && t != lexer.ItemColon {
+			break
+		}
+		item = p.next()
+	}
+	if n == 0 {
+		p.addSliceStep(bounds[0], bounds[0]+1, 1)
+		return Index(bounds[0]), nil
+	}
+	p.addSliceStep(bounds[0], bounds[1], bounds[2])
+	return Slice(bounds[0], bounds[1], bounds[2]), nil
+}
+
+// records a slice step. slices that count from the end of an array or step
+// backwards can not be evaluated on a stream.
+func (p *parser) addSliceStep(start, end, stride int) {
+	if start == Unbounded {
+		start = 0
+	}
+	if start < 0 || (end < 0 && end != Unbounded) || stride <= 0 {
+		p.unstreamable = true
+		return
+	}
+	p.steps = append(p.steps, step{start: start, end: end, stride: stride})
+}
+
+// parses a filter expression of terms separated by "||".
+func (p *parser) parseOr() (Selector, error) {
+	sel, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	terms := []Selector{sel}
+	for p.peek().Type == lexer.ItemOr {
+		debug("OR\n")
+		p.next()
+		sel, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, sel)
+	}
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+	return Or(terms...), nil
+}
+
+// parses a filter expression of factors separated by "&&".
+func (p *parser) parseAnd() (Selector, error) {
+	sel, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	factors := []Selector{sel}
+	for p.peek().Type == lexer.ItemAnd {
+		debug("AND\n")
+		p.next()
+		sel, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		factors = append(factors, sel)
 	}
+	if len(factors) == 1 {
+		return factors[0], nil
+	}
+	return And(factors...), nil
 }
 
-func parseBracket(lex lexer.Interface) (Selector, error) {
-	return nil, fmt.Errorf("not implemented")
+func (p *parser) parseNot() (Selector, error) {
+	if p.peek().Type != lexer.ItemNot {
+		return p.parseTest()
+	}
+	debug("NOT\n")
+	p.next()
+	sel, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	return Not(sel), nil
+}
+
+// parses a parenthesized expression, an existence test or a comparison.
+func (p *parser) parseTest() (Selector, error) {
+	if p.peek().Type == lexer.ItemLeftParen {
+		debug("LEFTPAREN\n")
+		p.next()
+		sel, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		switch item := p.next(); item.Type {
+		case lexer.ItemRightParen:
+			debug("RIGHTPAREN\n")
+			return sel, nil
+		case lexer.ItemError:
+			return nil, errors.New(item.Value)
+		default:
+			return nil, fmt.Errorf("expected \")\" but got %q", item.Value)
+		}
+	}
+	lhs, isPath, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	var cmp Comparison
+	switch p.peek().Type {
+	case lexer.ItemEqual:
+		cmp = Equal
+	case lexer.ItemNotEqual:
+		cmp = NotEqual
+	case lexer.ItemLess:
+		cmp = Less
+	case lexer.ItemLessEqual:
+		cmp = LessEqual
+	case lexer.ItemGreater:
+		cmp = Greater
+	case lexer.ItemGreaterEqual:
+		cmp = GreaterEqual
+	default:
+		if !isPath {
+			return nil, fmt.Errorf("expected comparison but got %q", p.peek().Value)
+		}
+		return Has(lhs, IgnoreErrors), nil
+	}
+	debugf("COMPARE %v\n", cmp)
+	p.next()
+	rhs, _, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return Compare(cmp, lhs, rhs), nil
+}
+
+// parses a path starting with "@" or "$", or a literal value. isPath is true
+// if the operand is a path.
+func (p *parser) parseOperand() (sel Selector, isPath bool, err error) {
+	switch item := p.peek(); item.Type {
+	case lexer.ItemAt, lexer.ItemDollar:
+		selectors, err := p.parsePath()
+		if err != nil {
+			return nil, false, err
+		}
+		if item.Type == lexer.ItemDollar {
+			selectors = append([]Selector{Root}, selectors...)
+		}
+		if len(selectors) == 1 {
+			return selectors[0], true, nil
+		}
+		return Chain(selectors...), true, nil
+	}
+	switch item := p.next(); item.Type {
+	case lexer.ItemError:
+		return nil, false, errors.New(item.Value)
+	case lexer.ItemNumber:
+		debugf("NUMBER %s\n", item.Value)
+		x, err := strconv.ParseFloat(item.Value, 64)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid number %q", item.Value)
+		}
+		return Value(numberLiteral(item.Value, x)), false, nil
+	case lexer.ItemString:
+		debugf("STRING %s\n", item.Value)
+		str, err := unquote(item.Value)
+		if err != nil {
+			return nil, false, err
+		}
+		return Value(jsontree.NewString(str)), false, nil
+	case lexer.ItemPathKey:
+		switch item.Value {
+		case "true":
+			return Value(jsontree.NewBoolean(true)), false, nil
+		case "false":
+			return Value(jsontree.NewBoolean(false)), false, nil
+		case "null":
+			return Value(jsontree.NewNull()), false, nil
+		}
+		fallthrough
+	default:
+		return nil, false, fmt.Errorf("expected value but got %q", item.Value)
+	}
+}
+
+// unquotes a single or double quoted string item.
+func unquote(s string) (string, error) {
+	if len(s) < 2 || s[0] != s[len(s)-1] {
+		return "", fmt.Errorf("invalid string %s", s)
+	}
+	if s[0] == '"' {
+		return strconv.Unquote(s)
+	}
+	// rewrite single quoted strings so strconv can handle the escapes.
+	buf := make([]byte, 0, len(s)+2)
+	buf = append(buf, '"')
+	body := s[1 : len(s)-1]
+	for i := 0; i < len(body); i++ {
+		switch c := body[i]; {
+		case c == '\\' && i+1 < len(body) && body[i+1] == '\'':
+			buf = append(buf, '\'')
+			i++
+		case c == '\\' && i+1 < len(body):
+			buf = append(buf, c, body[i+1])
+			i++
+		case c == '"':
+			buf = append(buf, '\\', '"')
+		default:
+			buf = append(buf, c)
+		}
+	}
+	buf = append(buf, '"')
+	str, err := strconv.Unquote(string(buf))
+	if err != nil {
+		return "", fmt.Errorf("invalid string %s", s)
+	}
+	return str, nil
+}
+
+// a number literal that keeps the digits of lit, so that it compares exactly
+// with numbers decoded using UseNumber. lit is parsed as x if it is not valid
+// JSON.
+func numberLiteral(lit string, x float64) *jsontree.JsonTree {
+	js := jsontree.New()
+	js.UseNumber()
+	if err := js.UnmarshalJSON([]byte(lit)); err != nil {
+		return jsontree.NewNumber(x)
+	}
+	return js
 }
@@ -0,0 +1,263 @@
+// Copyright 2013, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// stream.go [created: Sat, 17 Oct 2026]
+
+package jsonpath
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/bmatsuo/go-jsontree"
+)
+
+// returned by CompileStream for paths that can not be evaluated in a single
+// pass over a token stream.
+var ErrNotStreamable = errors.New("path can not be evaluated on a stream")
+
+// a path that is evaluated against the token stream of a json.Decoder.
+// values that do not match the path are skipped without being decoded, so
+// documents much larger than memory can be searched as long as the selected
+// values are small.
+type StreamPath struct {
+	// options for the trees of selected values, as for jsontree.NewOrdered
+	// and (*jsontree.JsonTree).UseNumber.
+	Ordered   bool
+	UseNumber bool
+
+	steps []step
+}
+
+// compiles a path for evaluation on a stream. only paths made of keys,
+// wildcards, non-negative indices and forward slices with non-negative bounds
+// can be streamed. other paths, such as those with filters, unions, recursive
+// descent or negative indices, cause ErrNotStreamable to be returned.
+func CompileStream(input string) (*StreamPath, error) {
+	p, _, err := parse(input)
+	if err != nil {
+		return nil, err
+	}
+	if p.unstreamable {
+		return nil, ErrNotStreamable
+	}
+	return &StreamPath{steps: p.steps}, nil
+}
+
+// a value selected from a stream. Offset is the input offset at which Value
+// begins. if the last step of the path is a key that could not be found then
+// Value is nil, Err is a *jsontree.PathError like the error Lookup would
+// return, and Offset is the offset of the value that should have contained
+// the key.
+type StreamResult struct {
+	Path   jsontree.Path
+	Value  *jsontree.JsonTree
+	Err    error
+	Offset int64
+}
+
+// reads the next top-level value from dec and calls fn with each value
+// selected by sp, in the order the values appear in the input. unlike Lookup,
+// object members matched by a wildcard are always selected in input order.
+// returns io.EOF if dec has no more values. if fn returns an error, Select
+// stops reading and returns it.
+func (sp *StreamPath) Select(dec *json.Decoder, fn func(StreamResult) error) error {
+	w := &streamWalker{sp: sp, dec: dec, fn: fn}
+	return w.walk()
+}
+
+// a step of a path evaluated on a stream. a step is a single key, all
+// members or elements, or a slice of an array.
+type step struct {
+	isKey bool
+	key   string
+	all   bool
+
+	start, end, stride int
+}
+
+func (s step) matchKey(key string) bool {
+	return s.all || (s.isKey && s.key == key)
+}
+
+func (s step) matchIndex(i int) bool {
+	if s.all {
+		return true
+	}
+	if s.isKey || i < s.start || (s.end != Unbounded && i >= s.end) {
+		return false
+	}
+	return (i-s.start)%s.stride == 0
+}
+
+type streamWalker struct {
+	sp   *StreamPath
+	dec  *json.Decoder
+	fn   func(StreamResult) error
+	path jsontree.Path
+}
+
+// processes the next value in the stream, which has matched the first
+// len(w.path) steps of the path.
+func (w *streamWalker) walk() error {
+	n := len(w.path)
+	if n == len(w.sp.steps) {
+		return w.emit()
+	}
+	st := w.sp.steps[n]
+	last := n == len(w.sp.steps)-1
+	start := w.offset()
+	tok, err := w.token(n > 0)
+	if err != nil {
+		return err
+	}
+	switch tok {
+	case json.Delim('{'):
+		found := false
+		for w.dec.More() {
+			tok, err := w.token(true)
+			if err != nil {
+				return err
+			}
+			key := tok.(string)
+			if st.matchKey(key) {
+				found = true
+				w.path = append(w.path, jsontree.KeySegment(key))
+				err = w.walk()
+				w.path = w.path[:n]
+			} else {
+				err = w.skip()
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if _, err := w.token(true); err != nil {
+			return err
+		}
+		if last && st.isKey && !found {
+			return w.fail(start, st.key, jsontree.ErrNoExist)
+		}
+	case json.Delim('['):
+		for i := 0; w.dec.More(); i++ {
+			var err error
+			if st.matchIndex(i) {
+				w.path = append(w.path, jsontree.IndexSegment(i))
+				err = w.walk()
+				w.path = w.path[:n]
+			} else {
+				err = w.skip()
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if _, err := w.token(true); err != nil {
+			return err
+		}
+		if last && st.isKey {
+			return w.fail(start, st.key, &jsontree.TypeError{Expected: jsontree.Object, Actual: jsontree.Array})
+		}
+	default:
+		if last && st.isKey {
+			return w.fail(start, st.key, &jsontree.TypeError{Expected: jsontree.Object, Actual: tokenType(tok)})
+		}
+	}
+	return nil
+}
+
+// decodes the next value and passes it to fn.
+func (w *streamWalker) emit() error {
+	var raw json.RawMessage
+	err := w.dec.Decode(&raw)
+	if err == io.EOF && len(w.path) > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return err
+	}
+	tree := jsontree.New()
+	if w.sp.Ordered {
+		tree = jsontree.NewOrdered()
+	}
+	if w.sp.UseNumber {
+		tree.UseNumber()
+	}
+	if err := tree.UnmarshalJSON(raw); err != nil {
+		return err
+	}
+	return w.fn(StreamResult{
+		Path:   append(jsontree.Path(nil), w.path...),
+		Value:  tree,
+		Offset: w.dec.InputOffset() - int64(len(raw)),
+	})
+}
+
+// reports that key could not be selected from the value at offset.
+func (w *streamWalker) fail(offset int64, key string, err error) error {
+	path := append(append(jsontree.Path(nil), w.path...), jsontree.KeySegment(key))
+	return w.fn(StreamResult{
+		Path:   path,
+		Err:    jsontree.NewPathError(path, err),
+		Offset: offset,
+	})
+}
+
+// skips the next value without decoding it.
+func (w *streamWalker) skip() error {
+	depth := 0
+	for {
+		tok, err := w.token(true)
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// the next token. the end of input is unexpected if nested is true.
+func (w *streamWalker) token(nested bool) (json.Token, error) {
+	tok, err := w.dec.Token()
+	if err == io.EOF && nested {
+		err = io.ErrUnexpectedEOF
+	}
+	return tok, err
+}
+
+// the input offset of the next token, as far as can be seen in the decoder's
+// buffer.
+func (w *streamWalker) offset() int64 {
+	off := w.dec.InputOffset()
+	var buf [1]byte
+	r := w.dec.Buffered()
+	for {
+		if n, _ := r.Read(buf[:]); n == 0 || strings.IndexByte(" \t\r\n,:", buf[0]) < 0 {
+			return off
+		}
+		off++
+	}
+}
+
+// the type of a scalar token.
+func tokenType(tok json.Token) jsontree.JsonType {
+	switch tok.(type) {
+	case string:
+		return jsontree.String
+	case float64, json.Number:
+		return jsontree.Number
+	case bool:
+		return jsontree.Boolean
+	}
+	return jsontree.Null
+}
@@ -0,0 +1,44 @@
+// Copyright 2013, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// generic.go [created: Sat, 17 Oct 2026]
+
+package jsontree
+
+// converts tree to a value of type T. strings, numbers and booleans are
+// converted directly and other types are converted with Decode. returns a
+// *PathError if tree has an error or can not be converted.
+//
+//	price, err := jsontree.As[float64](tree.Get("price"))
+func As[T any](tree *JsonTree) (T, error) {
+	var v T
+	var err error
+	switch p := any(&v).(type) {
+	case *string:
+		*p, err = tree.String()
+	case *float64:
+		*p, err = tree.Number()
+	case *bool:
+		*p, err = tree.Boolean()
+	case *int64:
+		*p, err = tree.Int64()
+	case **JsonTree:
+		if err = tree.Err(); err == nil {
+			*p = tree
+		}
+	default:
+		err = tree.Decode(&v)
+	}
+	return v, err
+}
+
+// looks up the value of tree at path and converts it to a value of type T.
+// each element of path is an object key or, for arrays, a decimal index, as
+// in a JSON Pointer. returns a *PathError if the value does not exist or can
+// not be converted.
+//
+//	name, err := jsontree.GetAs[string](tree, "items", "0", "name")
+func GetAs[T any](tree *JsonTree, path ...string) (T, error) {
+	return As[T](Pointer(path).Lookup(tree))
+}
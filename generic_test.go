@@ -0,0 +1,64 @@
+// Copyright 2013, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// generic_test.go [created: Sat, 17 Oct 2026]
+
+package jsontree
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAs(t *testing.T) {
+	tree := mustUnmarshal(t, `{"items":[{"name":"a","price":2,"tags":["x"],"ok":true,"nothing":null}]}`)
+	if name, err := GetAs[string](tree, "items", "0", "name"); err != nil || name != "a" {
+		t.Errorf("name: %q %v", name, err)
+	}
+	if price, err := GetAs[int](tree, "items", "0", "price"); err != nil || price != 2 {
+		t.Errorf("price: %v %v", price, err)
+	}
+	if ok, err := GetAs[bool](tree, "items", "0", "ok"); err != nil || !ok {
+		t.Errorf("ok: %v %v", ok, err)
+	}
+	if tags, err := GetAs[[]string](tree, "items", "0", "tags"); err != nil || len(tags) != 1 {
+		t.Errorf("tags: %v %v", tags, err)
+	}
+	if item, err := As[*JsonTree](tree.Get("items").GetIndex(0)); err != nil || item.Parent() == nil {
+		t.Errorf("tree: %v %v", item, err)
+	}
+	if _, err := GetAs[string](tree, "items", "1", "name"); !errors.Is(err, ErrIndexOutOfRange) {
+		t.Errorf("missing: %v", err)
+	}
+	var terr *TypeError
+	if _, err := GetAs[float64](tree, "items", "0", "name"); !errors.As(err, &terr) {
+		t.Errorf("mismatch: %v", err)
+	}
+
+	item := tree.Get("items").GetIndex(0)
+	for _, test := range []struct {
+		key     string
+		expect  string
+		typeErr bool
+	}{
+		{"name", "a", false},
+		{"missing", "def", false},
+		{"nothing", "def", false},
+		{"price", "", true},
+	} {
+		s, err := item.Get(test.key).StringOr("def")
+		if s != test.expect || errors.As(err, &terr) != test.typeErr {
+			t.Errorf("StringOr %s: %q %v", test.key, s, err)
+		}
+	}
+	if x, err := item.Get("missing").Get("deeper").NumberOr(7); err != nil || x != 7 {
+		t.Errorf("NumberOr: %v %v", x, err)
+	}
+	if _, err := item.Get("name").Get("x").BooleanOr(true); err == nil {
+		t.Error("BooleanOr through a string did not fail")
+	}
+	if b, err := item.Get("ok").BooleanOr(false); err != nil || !b {
+		t.Errorf("BooleanOr: %v %v", b, err)
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2013, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// iter.go [created: Sat, 17 Oct 2026]
+
+package jsontree
+
+import (
+	"iter"
+)
+
+// an iterator over the keys and members of the object tree, in the order
+// given by Keys. each child has tree as its parent. yields nothing if tree is
+// not an object.
+//
+//	for key, child := range tree.All() {
+//		...
+//	}
+func (tree *JsonTree) All() iter.Seq2[string, *JsonTree] {
+	return func(yield func(string, *JsonTree) bool) {
+		if tree.checkType(Object) != nil {
+			return
+		}
+		for _, k := range objectKeys(tree.val) {
+			if !yield(k, tree.Get(k)) {
+				return
+			}
+		}
+	}
+}
+
+// an iterator over the indices and elements of the array tree. each child has
+// tree as its parent. yields nothing if tree is not an array.
+func (tree *JsonTree) Elements() iter.Seq2[int, *JsonTree] {
+	return func(yield func(int, *JsonTree) bool) {
+		if tree.checkType(Array) != nil {
+			return
+		}
+		for i := range tree.val.([]interface{}) {
+			if !yield(i, tree.GetIndex(i)) {
+				return
+			}
+		}
+	}
+}
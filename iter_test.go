@@ -0,0 +1,39 @@
+// Copyright 2013, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// iter_test.go [created: Sat, 17 Oct 2026]
+
+package jsontree
+
+import (
+	"testing"
+)
+
+func TestAll(t *testing.T) {
+	tree := mustUnmarshal(t, `{"b":[10,20,30],"a":null}`)
+	var keys string
+	for k, child := range tree.All() {
+		keys += k
+		if child.Parent() != tree {
+			t.Errorf("member %q has the wrong parent", k)
+		}
+	}
+	if keys != "ab" {
+		t.Errorf("keys: %q", keys)
+	}
+	var sum float64
+	for i, child := range tree.Get("b").Elements() {
+		x, _ := child.Number()
+		sum += x
+		if i == 1 {
+			break
+		}
+	}
+	if sum != 30 {
+		t.Errorf("sum: %v", sum)
+	}
+	for range tree.Get("a").All() {
+		t.Error("null has members")
+	}
+}
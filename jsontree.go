@@ -13,17 +13,31 @@ import (
 	"fmt"
 )
 
-// an error that includes path information
+// errors describing why a value could not be retrieved. they are wrapped in a
+// *PathError and can be detected with errors.Is.
+var (
+	ErrUninitialized   = errors.New("uninitialized")
+	ErrNoExist         = errors.New("key does not exist")
+	ErrIndexOutOfRange = errors.New("index out of range")
+)
+
+// an error for a value that is not of the expected type. it is wrapped in a
+// *PathError and can be retrieved with errors.As.
+type TypeError struct {
+	Expected JsonType
+	Actual   JsonType
+}
+
+func (err *TypeError) Error() string {
+	return fmt.Sprintf(typeErrorFormat(err.Expected), err.Expected, err.Actual)
+}
+
+// an error that includes path information. Pos is the source position of the
+// value, or of its nearest ancestor, if positions were tracked when decoding.
 type PathError struct {
 	Path string
 	Err  error
-}
-
-func newPathError(path string, v ...interface{}) *PathError {
-	return &PathError{
-		Path: path,
-		Err:  errors.New(fmt.Sprint(v...)),
-	}
+	Pos  Position
 }
 
 func newPathErrorf(path, format string, v ...interface{}) *PathError {
@@ -34,9 +48,17 @@ func newPathErrorf(path, format string, v ...interface{}) *PathError {
 }
 
 func (err *PathError) Error() string {
+	if err.Pos.IsValid() {
+		return fmt.Sprintf("%v: %v; %s", err.Pos, err.Err, err.Path)
+	}
 	return fmt.Sprintf("%v; %s", err.Err, err.Path)
 }
 
+// the underlying error, such as ErrNoExist or a *TypeError.
+func (err *PathError) Unwrap() error {
+	return err.Err
+}
+
 type JsonType uint8
 
 const (
@@ -75,6 +97,13 @@ type JsonTree struct {
 	index  int
 	err    *error
 	val    interface{}
+
+	ordered   bool
+	useNumber bool
+	lazy      bool
+	source    *lazyValue // the lazy value val was decoded from
+	trackPos  *Position
+	pos       *posNode
 }
 
 func newTree(val interface{}) *JsonTree {
@@ -135,15 +164,19 @@ func (tree *JsonTree) Type() JsonType {
 	return tree.typ
 }
 
+// the number of members of the object tree or elements of the array tree.
+// returns a *PathError wrapping a *TypeError if tree is not an object or an
+// array.
 func (tree *JsonTree) Len() (int, error) {
-	switch tree.Type() {
-	case Object:
-		return len(tree.val.(map[string]interface{})), nil
-	case Array:
-		return len(tree.val.([]interface{})), nil
-	default:
-		return 0, fmt.Errorf("not an array or an object (%v); %s", tree.Type, tree.path())
+	switch {
+	case !tree.init:
+		return 0, tree.pathError(ErrUninitialized)
+	case tree.typ == Error:
+		return 0, *tree.err
+	case tree.typ != Object && tree.typ != Array:
+		return 0, tree.pathError(&TypeError{Expected: Object, Actual: tree.typ})
 	}
+	return containerLen(tree.container()), nil
 }
 
 // any error encountered due to non-existent keys, out of range indices, etc.
@@ -172,16 +205,8 @@ func (tree *JsonTree) Parent() *JsonTree {
 // if tree is not an array then the Err() method of the returned *JsonTree's
 // returns a *PathError.
 func (tree *JsonTree) GetIndex(i int) *JsonTree {
-	child := New()
+	child := tree.child("", i)
 	defer child.getType()
-	child.index = i
-	if tree.root == nil {
-		child.root = tree
-	} else {
-		child.root = tree.root
-	}
-	child.parent = tree
-	child.err = tree.err
 	if child.err != nil {
 		return child
 	}
@@ -189,14 +214,15 @@ func (tree *JsonTree) GetIndex(i int) *JsonTree {
 	case !tree.init:
 		child.errUninitialized()
 	case tree.typ == Array:
-		a := tree.val.([]interface{})
-		if 0 <= i && i < len(a) {
-			child.val = a[i]
+		val, ok := arrayElem(tree.container(), i)
+		if ok {
+			child.val = val
+			child.loadScalar()
 		} else {
 			child.errIndexOutOfRange()
 		}
 	default:
-		child.errTypeError(Array)
+		child.errTypeError(Array, tree.typ)
 	}
 	return child
 }
@@ -206,16 +232,8 @@ func (tree *JsonTree) GetIndex(i int) *JsonTree {
 // if tree is not an object then the Err() method of the returned *JsonTree's
 // returns a *PathError.
 func (tree *JsonTree) Get(key string) *JsonTree {
-	child := New()
+	child := tree.child(key, -1)
 	defer child.getType()
-	child.key = key
-	if tree.root == nil {
-		child.root = tree
-	} else {
-		child.root = tree.root
-	}
-	child.parent = tree
-	child.err = tree.err
 	if child.err != nil {
 		return child
 	}
@@ -223,26 +241,54 @@ func (tree *JsonTree) Get(key string) *JsonTree {
 	case !tree.init:
 		child.errUninitialized()
 	case tree.typ == Object:
-		val, ok := tree.val.(map[string]interface{})[key]
+		val, ok := objectMember(tree.container(), key)
 		if ok {
 			child.val = val
+			child.loadScalar()
 		} else {
 			child.errNoExist()
 		}
 	default:
-		child.errTypeError(Object)
+		child.errTypeError(Object, tree.typ)
+	}
+	return child
+}
+
+// an uninitialized child of tree at key or index i. the child inherits any
+// error from tree.
+func (tree *JsonTree) child(key string, i int) *JsonTree {
+	child := New()
+	child.key = key
+	child.index = i
+	if tree.root == nil {
+		child.root = tree
+	} else {
+		child.root = tree.root
+	}
+	child.parent = tree
+	child.err = tree.err
+	if tree.pos != nil {
+		child.pos = tree.pos.child(key, i)
 	}
 	return child
 }
 
+// the value of tree as json.Unmarshal would decode it into an interface{}.
+// returns a *PathError if tree is uninitialized or has an error.
 func (tree *JsonTree) Interface() (interface{}, error) {
+	if !tree.init {
+		return nil, tree.pathError(ErrUninitialized)
+	}
+	if err := tree.load(); err != nil {
+		return nil, err
+	}
 	return tree.val, tree.Err()
 }
 
 // converts tree to a string. returns a *PathError if tree is not a string.
 func (tree *JsonTree) String() (string, error) {
 	if !tree.init {
-		return "", newPathErrorf(tree.path(), "uninitialized")
+		return "", tree.pathError(ErrUninitialized)
 	}
 	switch tree.typ {
 	case Error:
@@ -250,29 +296,29 @@ func (tree *JsonTree) String() (string, error) {
 	case String:
 		return tree.val.(string), nil
 	default:
-		return "", newPathErrorf(tree.path(), "not a string (%v)", tree.Type)
+		return "", tree.pathError(&TypeError{Expected: String, Actual: tree.Type()})
 	}
 }
 
 // converts tree to a number. returns a *PathError if tree is not a number.
 func (tree *JsonTree) Number() (float64, error) {
 	if !tree.init {
-		return 0, newPathErrorf(tree.path(), "uninitialized")
+		return 0, tree.pathError(ErrUninitialized)
 	}
 	switch tree.typ {
 	case Error:
 		return 0, *tree.err
 	case Number:
-		return tree.val.(float64), nil
+		return tree.float64()
 	default:
-		return 0, newPathErrorf(tree.path(), "not a number (%v)", tree.Type)
+		return 0, tree.pathError(&TypeError{Expected: Number, Actual: tree.Type()})
 	}
 }
 
 // converts tree to a bool. returns a *PathError if tree is not a boolean.
 func (tree *JsonTree) Boolean() (bool, error) {
 	if !tree.init {
-		return false, newPathErrorf(tree.path(), "uninitialized")
+		return false, tree.pathError(ErrUninitialized)
 	}
 	switch tree.typ {
 	case Error:
@@ -280,37 +326,44 @@ func (tree *JsonTree) Boolean() (bool, error) {
 	case Boolean:
 		return tree.val.(bool), nil
 	default:
-		return false, newPathErrorf(tree.path(), "not a bool (%v)", tree.Type)
+		return false, tree.pathError(&TypeError{Expected: Boolean, Actual: tree.Type()})
 	}
 }
 
 // converts tree to a slice. returns a *PathError if tree is not an array.
 func (tree *JsonTree) Array() ([]interface{}, error) {
 	if !tree.init {
-		return nil, newPathErrorf(tree.path(), "uninitialized")
+		return nil, tree.pathError(ErrUninitialized)
 	}
 	switch tree.typ {
 	case Error:
 		return nil, *tree.err
 	case Array:
+		if err := tree.load(); err != nil {
+			return nil, err
+		}
 		return tree.val.([]interface{}), nil
 	default:
-		return nil, newPathErrorf(tree.path(), "not an array (%v)", tree.Type)
+		return nil, tree.pathError(&TypeError{Expected: Array, Actual: tree.Type()})
 	}
 }
 
-// converts tree to a map. returns a *PathError if tree is not an object.
+// converts tree to a map. returns a *PathError if tree is not an object. if
+// tree is ordered the map must not be modified directly.
 func (tree *JsonTree) Object() (map[string]interface{}, error) {
 	if !tree.init {
-		return nil, newPathErrorf(tree.path(), "uninitialized")
+		return nil, tree.pathError(ErrUninitialized)
 	}
 	switch tree.typ {
 	case Error:
 		return nil, *tree.err
 	case Object:
-		return tree.val.(map[string]interface{}), nil
+		if err := tree.load(); err != nil {
+			return nil, err
+		}
+		return objectMap(tree.val), nil
 	default:
-		return nil, newPathErrorf(tree.path(), "not an object (%v)", tree.Type)
+		return nil, tree.pathError(&TypeError{Expected: Object, Actual: tree.Type()})
 	}
 }
 
@@ -323,51 +376,63 @@ func (tree *JsonTree) IsNull() bool {
 // implements json.Unmarshaler
 func (tree *JsonTree) UnmarshalJSON(p []byte) error {
 	defer tree.getType()
+	tree.source = nil
+	if tree.lazy && tree.trackPos == nil && isContainer(p) {
+		return tree.unmarshalLazy(p)
+	}
+	if tree.ordered || tree.useNumber || tree.trackPos != nil {
+		return tree.unmarshalTokens(p)
+	}
 	return json.Unmarshal(p, &tree.val)
 }
 
 // implements json.Marshaler
 func (tree *JsonTree) MarshalJSON() ([]byte, error) {
+	if lv, ok := tree.val.(*lazyValue); ok {
+		return lv.MarshalJSON()
+	}
+	if tree.source != nil {
+		return tree.source.MarshalJSON()
+	}
 	return json.Marshal(tree.val)
 }
 
-func (tree *JsonTree) newError(v ...interface{}) {
-	err := error(newPathError(tree.path(), v...))
+func (tree *JsonTree) setError(err error) {
+	err = tree.pathError(err)
 	tree.err = &err
 }
 
-func (tree *JsonTree) newErrorf(format string, v ...interface{}) {
-	err := error(newPathErrorf(tree.path(), format, v...))
-	tree.err = &err
+// a *PathError wrapping err at the location of tree.
+func (tree *JsonTree) pathError(err error) *PathError {
+	return &PathError{Path: tree.path(), Err: err, Pos: tree.errorPosition()}
+}
+
+func (tree *JsonTree) pathErrorf(format string, v ...interface{}) *PathError {
+	return tree.pathError(fmt.Errorf(format, v...))
 }
 
 func (tree *JsonTree) errUninitialized() {
-	tree.newError("uninitialized")
+	tree.setError(ErrUninitialized)
 }
 func (tree *JsonTree) errNoExist() {
-	tree.newError("key does not exist")
+	tree.setError(ErrNoExist)
 }
 func (tree *JsonTree) errIndexOutOfRange() {
-	tree.newErrorf("index out of range")
+	tree.setError(ErrIndexOutOfRange)
 }
-func (tree *JsonTree) errTypeError(expected JsonType) {
-	if expected == Object && expected == Array {
-		tree.newErrorf("not an %v (%v)", expected, tree.Type)
-	} else {
-		tree.newErrorf("not a %v (%v)", expected, tree.Type)
+func (tree *JsonTree) errTypeError(expected, actual JsonType) {
+	tree.setError(&TypeError{Expected: expected, Actual: actual})
+}
+
+func typeErrorFormat(expected JsonType) string {
+	if expected == Object || expected == Array {
+		return "not an %v (%v)"
 	}
+	return "not a %v (%v)"
 }
 
 func (tree *JsonTree) path() string {
-	if tree.parent == nil {
-		return "$"
-	}
-	pre := tree.parent.path()
-	if tree.index >= 0 {
-		return fmt.Sprintf("%s[%d]", pre, tree.index)
-	} else {
-		return fmt.Sprintf("%s.%s", pre, tree.key)
-	}
+	return tree.Path().errorPath()
 }
 
 func (tree *JsonTree) getType() {
@@ -376,10 +441,10 @@ func (tree *JsonTree) getType() {
 		tree.typ = Error
 		return
 	}
-	switch tree.val.(type) {
+	switch v := tree.val.(type) {
 	case string:
 		tree.typ = String
-	case float64:
+	case float64, json.Number:
 		tree.typ = Number
 	case bool:
 		tree.typ = Boolean
@@ -387,7 +452,9 @@ func (tree *JsonTree) getType() {
 		tree.typ = Null
 	case []interface{}:
 		tree.typ = Array
-	case map[string]interface{}:
+	case map[string]interface{}, *OrderedObject:
 		tree.typ = Object
+	case *lazyValue:
+		tree.typ = v.jsonType()
 	}
 }
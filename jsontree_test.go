@@ -1,8 +1,1222 @@
 package jsontree
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestJsonTree(t *testing.T) {
 }
+
+func mustUnmarshal(t *testing.T, js string) *JsonTree {
+	tree := New()
+	err := tree.UnmarshalJSON([]byte(js))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+func testMarshal(t *testing.T, tree *JsonTree, expect string) {
+	p, err := tree.MarshalJSON()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(p) != expect {
+		t.Errorf("expected %s got %s", expect, p)
+	}
+}
+
+func TestMutation(t *testing.T) {
+	tree := mustUnmarshal(t, `{"a":{"b":[1,2,3]},"c":"x"}`)
+	b := tree.Get("a").Get("b")
+	if err := b.Append(NewString("four")); err != nil {
+		t.Fatal(err)
+	}
+	testMarshal(t, tree.Root(), `{"a":{"b":[1,2,3,"four"]},"c":"x"}`)
+	if err := b.Insert(0, NewBoolean(true)); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SetIndex(1, NewNull()); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.DeleteIndex(2); err != nil {
+		t.Fatal(err)
+	}
+	testMarshal(t, tree, `{"a":{"b":[true,null,3,"four"]},"c":"x"}`)
+	if err := tree.Set("d", NewNumber(4)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Delete("c"); err != nil {
+		t.Fatal(err)
+	}
+	testMarshal(t, tree, `{"a":{"b":[true,null,3,"four"]},"d":4}`)
+
+	nested := mustUnmarshal(t, `[[1],[2]]`)
+	if err := nested.GetIndex(1).Append(NewNumber(3)); err != nil {
+		t.Fatal(err)
+	}
+	testMarshal(t, nested, `[[1],[2,3]]`)
+
+	// stored values are copies
+	if err := tree.Set("e", tree.Get("a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Get("a").Set("b", NewNull()); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Set("self", tree); err != nil {
+		t.Fatal(err)
+	}
+	testMarshal(t, tree, `{"a":{"b":null},"d":4,"e":{"b":[true,null,3,"four"]},`+
+		`"self":{"a":{"b":null},"d":4,"e":{"b":[true,null,3,"four"]}}}`)
+}
+
+func TestMutationErrors(t *testing.T) {
+	tree := mustUnmarshal(t, `{"a":[1],"s":"x"}`)
+	for _, err := range []error{
+		tree.Get("s").Set("k", NewNull()),
+		tree.Get("s").Append(NewNull()),
+		tree.Get("a").SetIndex(1, NewNull()),
+		tree.Get("a").Insert(2, NewNull()),
+		tree.Get("a").DeleteIndex(-1),
+		tree.Delete("nope"),
+		tree.Set("k", tree.Get("nope")),
+		New().Set("k", NewNull()),
+	} {
+		if _, ok := err.(*PathError); !ok {
+			t.Errorf("expected *PathError got %#v", err)
+		}
+	}
+	err := tree.Get("s").Append(NewNull())
+	if err.Error() != "not an array (string); $.s" {
+		t.Errorf("unexpected message: %v", err)
+	}
+	testMarshal(t, tree, `{"a":[1],"s":"x"}`)
+}
+
+func TestOrdered(t *testing.T) {
+	src := `{"z":1,"a":{"y":[{"q":1,"b":2}],"x":null},"m":"s"}`
+	tree := NewOrdered()
+	err := tree.UnmarshalJSON([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testMarshal(t, tree, src)
+	keys, err := tree.Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 3 || keys[0] != "z" || keys[1] != "a" || keys[2] != "m" {
+		t.Errorf("unexpected keys %q", keys)
+	}
+	if x, _ := tree.Get("a").Get("y").GetIndex(0).Get("b").Number(); x != 2 {
+		t.Errorf("unexpected value %v", x)
+	}
+	testMarshal(t, tree.Get("a"), `{"y":[{"q":1,"b":2}],"x":null}`)
+
+	if err := tree.Get("a").Set("c", NewString("new")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Delete("z"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Set("m", NewBoolean(true)); err != nil {
+		t.Fatal(err)
+	}
+	testMarshal(t, tree, `{"a":{"y":[{"q":1,"b":2}],"x":null,"c":"new"},"m":true}`)
+
+	if err := NewOrdered().UnmarshalJSON([]byte(`{} {}`)); err == nil {
+		t.Errorf("expected error for trailing data")
+	}
+	if err := NewOrdered().UnmarshalJSON([]byte(`{"a":}`)); err == nil {
+		t.Errorf("expected error for invalid json")
+	}
+}
+
+func TestKeys(t *testing.T) {
+	tree := mustUnmarshal(t, `{"c":1,"a":2,"b":3}`)
+	keys, err := tree.Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 3 || keys[0] != "a" || keys[1] != "b" || keys[2] != "c" {
+		t.Errorf("unexpected keys %q", keys)
+	}
+	if _, err := tree.Get("a").Keys(); err == nil {
+		t.Errorf("expected error")
+	}
+}
+
+func TestUseNumber(t *testing.T) {
+	src := `{"id":12345678901234567890,"neg":-9007199254740993,"f":1.5,"e":1e3,"big":1e400}`
+	tree := New()
+	tree.UseNumber()
+	if err := tree.UnmarshalJSON([]byte(src)); err != nil {
+		t.Fatal(err)
+	}
+	testMarshal(t, tree, `{"big":1e400,"e":1e3,"f":1.5,"id":12345678901234567890,"neg":-9007199254740993}`)
+
+	if x, err := tree.Get("id").Uint64(); err != nil || x != 12345678901234567890 {
+		t.Errorf("unexpected uint64 %v %v", x, err)
+	}
+	if _, err := tree.Get("id").Int64(); err == nil {
+		t.Errorf("expected int64 overflow")
+	}
+	if x, err := tree.Get("neg").Int64(); err != nil || x != -9007199254740993 {
+		t.Errorf("unexpected int64 %v %v", x, err)
+	}
+	if _, err := tree.Get("neg").Uint64(); err == nil {
+		t.Errorf("expected uint64 overflow")
+	}
+	if x, err := tree.Get("e").Int64(); err != nil || x != 1000 {
+		t.Errorf("unexpected int64 %v %v", x, err)
+	}
+	if _, err := tree.Get("f").Int64(); err == nil {
+		t.Errorf("expected non-integral error")
+	}
+	if x, err := tree.Get("f").Number(); err != nil || x != 1.5 {
+		t.Errorf("unexpected float64 %v %v", x, err)
+	}
+	if _, err := tree.Get("big").Float64(); err == nil {
+		t.Errorf("expected float64 overflow")
+	}
+	if x, err := tree.Get("big").BigInt(); err != nil || len(x.String()) != 401 {
+		t.Errorf("unexpected big.Int %v %v", x, err)
+	}
+	if _, err := tree.Get("big").Int64(); err == nil || err.Error() != "number 1e400 overflows int64; $.big" {
+		t.Errorf("unexpected int64 overflow %v", err)
+	}
+	if _, err := tree.Get("big").Uint64(); err == nil || err.Error() != "number 1e400 overflows uint64; $.big" {
+		t.Errorf("unexpected uint64 overflow %v", err)
+	}
+	if x, err := tree.Get("id").BigFloat(); err != nil || x.Text('f', 0) != "12345678901234567890" {
+		t.Errorf("unexpected big.Float %v %v", x, err)
+	}
+	if s, err := tree.Get("neg").NumberString(); err != nil || s != "-9007199254740993" {
+		t.Errorf("unexpected string %q %v", s, err)
+	}
+	for _, err := range []error{
+		func() error { _, err := tree.Get("nope").Int64(); return err }(),
+		func() error { _, err := NewString("1").BigInt(); return err }(),
+		func() error { _, err := NewString("1").NumberString(); return err }(),
+	} {
+		if _, ok := err.(*PathError); !ok {
+			t.Errorf("expected *PathError got %#v", err)
+		}
+	}
+
+	huge := New()
+	huge.UseNumber()
+	if err := huge.UnmarshalJSON([]byte(`1e999999999`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := huge.BigInt(); err == nil {
+		t.Errorf("expected exponent error")
+	}
+}
+
+func TestNumberFloat64(t *testing.T) {
+	tree := mustUnmarshal(t, `[3, 2.5, 1e20]`)
+	if x, err := tree.GetIndex(0).Int64(); err != nil || x != 3 {
+		t.Errorf("unexpected int64 %v %v", x, err)
+	}
+	if _, err := tree.GetIndex(1).Int64(); err == nil {
+		t.Errorf("expected non-integral error")
+	}
+	if _, err := tree.GetIndex(2).Int64(); err == nil {
+		t.Errorf("expected int64 overflow")
+	}
+	if s, _ := tree.GetIndex(1).NumberString(); s != "2.5" {
+		t.Errorf("unexpected string %q", s)
+	}
+}
+
+func TestPointer(t *testing.T) {
+	tree := mustUnmarshal(t, `{"a":[{"b/c":1,"m~n":2}],"":3,"x":{"01":4}}`)
+	for ptr, expect := range map[string]string{
+		"":          `{"":3,"a":[{"b/c":1,"m~n":2}],"x":{"01":4}}`,
+		"/a/0/b~1c": `1`,
+		"/a/0/m~0n": `2`,
+		"/":         `3`,
+		"/x/01":     `4`,
+	} {
+		js := tree.Pointer(ptr)
+		if err := js.Err(); err != nil {
+			t.Errorf("%q: %v", ptr, err)
+			continue
+		}
+		testMarshal(t, js, expect)
+		if s := js.JSONPointer(); s != ptr {
+			t.Errorf("expected pointer %q got %q", ptr, s)
+		}
+	}
+	for _, ptr := range []string{"a", "/a/01", "/a/-", "/a/1", "/a/x", "/nope", "/a/0/b~2c", "/a/0/b~"} {
+		if _, ok := tree.Pointer(ptr).Err().(*PathError); !ok {
+			t.Errorf("%q: expected *PathError", ptr)
+		}
+	}
+	if err := tree.Pointer("/a/01").Err(); err == nil || err.Error() != `invalid array index "01"; /a/01` {
+		t.Errorf("invalid index error: %v", err)
+	}
+
+	p, err := ParsePointer("/a~1b/~0/0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p) != 3 || p[0] != "a/b" || p[1] != "~" || p[2] != "0" {
+		t.Errorf("unexpected pointer %q", p)
+	}
+	if p.String() != "/a~1b/~0/0" {
+		t.Errorf("unexpected string %q", p.String())
+	}
+	if s := (Pointer{"~1"}).String(); s != "/~01" {
+		t.Errorf("unexpected string %q", s)
+	}
+}
+
+func TestCopySetValue(t *testing.T) {
+	tree := NewOrdered()
+	if err := tree.UnmarshalJSON([]byte(`{"b":[1,{"c":2}],"a":3}`)); err != nil {
+		t.Fatal(err)
+	}
+	c := tree.Get("b").Copy()
+	if err := c.GetIndex(1).Set("c", NewNull()); err != nil {
+		t.Fatal(err)
+	}
+	testMarshal(t, c, `[1,{"c":null}]`)
+	testMarshal(t, tree, `{"b":[1,{"c":2}],"a":3}`)
+	if c.Parent() != nil || c.Root() != c {
+		t.Errorf("copy has a parent")
+	}
+
+	if err := tree.Get("b").SetValue(NewString("x")); err != nil {
+		t.Fatal(err)
+	}
+	testMarshal(t, tree, `{"b":"x","a":3}`)
+	if err := tree.SetValue(NewNumber(1)); err != nil {
+		t.Fatal(err)
+	}
+	testMarshal(t, tree, `1`)
+	if err := tree.Get("nope").SetValue(NewNull()); err == nil {
+		t.Errorf("expected error")
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	for _, test := range []struct{ target, patch, expect string }{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	} {
+		target := mustUnmarshal(t, test.target)
+		patch := mustUnmarshal(t, test.patch)
+		if err := target.MergePatch(patch); err != nil {
+			t.Error(err)
+			continue
+		}
+		testMarshal(t, target, test.expect)
+	}
+
+	tree := mustUnmarshal(t, `{"a":{"b":1},"c":2}`)
+	if err := tree.Get("a").MergePatch(mustUnmarshal(t, `{"d":3}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Get("c").MergePatch(mustUnmarshal(t, `{"e":4}`)); err != nil {
+		t.Fatal(err)
+	}
+	testMarshal(t, tree, `{"a":{"b":1,"d":3},"c":{"e":4}}`)
+
+	ordered := NewOrdered()
+	if err := ordered.UnmarshalJSON([]byte(`{"z":1,"a":2}`)); err != nil {
+		t.Fatal(err)
+	}
+	patch := NewOrdered()
+	if err := patch.UnmarshalJSON([]byte(`{"y":{"q":1,"p":2},"z":null}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := ordered.MergePatch(patch); err != nil {
+		t.Fatal(err)
+	}
+	testMarshal(t, ordered, `{"a":2,"y":{"q":1,"p":2}}`)
+
+	// objects created by a plain patch take the representation of the tree
+	if err := ordered.MergePatch(mustUnmarshal(t, `{"a":{"z":1,"b":2}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := ordered.Get("a").Set("c", NewNull()); err != nil {
+		t.Fatal(err)
+	}
+	testMarshal(t, ordered, `{"a":{"b":2,"z":1,"c":null},"y":{"q":1,"p":2}}`)
+}
+
+func TestCreateMergePatch(t *testing.T) {
+	for _, test := range []struct{ a, b, expect string }{
+		{`{"a":1,"b":{"c":2,"d":3},"e":[1]}`, `{"b":{"c":2,"d":4},"e":[1],"f":"x"}`, `{"a":null,"b":{"d":4},"f":"x"}`},
+		{`{"a":1}`, `{"a":1}`, `{}`},
+		{`{"a":1}`, `[1]`, `[1]`},
+		{`{"a":{"b":1}}`, `{"a":"s"}`, `{"a":"s"}`},
+	} {
+		a := mustUnmarshal(t, test.a)
+		b := mustUnmarshal(t, test.b)
+		patch, err := CreateMergePatch(a, b)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		testMarshal(t, patch, test.expect)
+		if err := a.MergePatch(patch); err != nil {
+			t.Error(err)
+			continue
+		}
+		if !valuesEqual(a.val, b.val) {
+			t.Errorf("patch %s did not produce %s", test.expect, test.b)
+		}
+	}
+
+	a := New()
+	a.UseNumber()
+	if err := a.UnmarshalJSON([]byte(`{"n":1.0}`)); err != nil {
+		t.Fatal(err)
+	}
+	patch, _ := CreateMergePatch(a, mustUnmarshal(t, `{"n":1}`))
+	testMarshal(t, patch, `{}`)
+}
+
+func TestDiff(t *testing.T) {
+	a := mustUnmarshal(t, `{"a":1,"b":{"c":"x","d":[1,2,3]},"e":true,"f":null}`)
+	b := mustUnmarshal(t, `{"a":1.0,"b":{"c":"y","d":[1,5]},"e":"true","g":[]}`)
+	changes, err := Diff(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{
+		`$.b.c: changed "x" to "y"`,
+		`$.b.d[1]: changed 2 to 5`,
+		`$.b.d[2]: removed 3`,
+		`$.e: changed boolean true to string "true"`,
+		`$.f: removed null`,
+		`$.g: added []`,
+	}
+	if len(changes) != len(expect) {
+		t.Fatalf("changes: %v", changes)
+	}
+	for i := range expect {
+		if changes[i].String() != expect[i] {
+			t.Errorf("change %d: %q != %q", i, changes[i], expect[i])
+		}
+	}
+	p, err := json.Marshal(changes[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(p) != `{"kind":"changed","path":"$.b.c","old":"x","new":"y"}` {
+		t.Errorf("json: %s", p)
+	}
+
+	a = mustUnmarshal(t, `[{"id":1,"v":"a"},{"id":2,"v":"b"},3,4]`)
+	b = mustUnmarshal(t, `[4,{"id":3,"v":"c"},{"id":2,"v":"B"},{"id":1,"v":"a"}]`)
+	changes, err = DiffOptions{UnorderedArrays: true, ArrayKey: "id"}.Diff(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect = []string{
+		`$[1].v: changed "b" to "B"`,
+		`$[2]: removed 3`,
+		`$[1]: added {"id":3,"v":"c"}`,
+	}
+	if len(changes) != len(expect) {
+		t.Fatalf("set changes: %v", changes)
+	}
+	for i := range expect {
+		if changes[i].String() != expect[i] {
+			t.Errorf("set change %d: %q != %q", i, changes[i], expect[i])
+		}
+	}
+
+	if _, err := Diff(New(), b); err == nil {
+		t.Error("expected error for uninitialized tree")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	ordered := []string{
+		`null`, `false`, `true`, `-1`, `0`, `1e-2`, `2`, `""`, `"a"`, `"b"`,
+		`[]`, `[null]`, `[1]`, `[1,2]`, `[2]`, `{}`, `{"a":1}`, `{"a":1,"b":0}`,
+		`{"a":2}`, `{"b":0}`,
+	}
+	for i := range ordered {
+		for j := range ordered {
+			a := mustUnmarshal(t, ordered[i])
+			b := mustUnmarshal(t, ordered[j])
+			expect := compareInts(i, j)
+			if c := Compare(a, b); c != expect {
+				t.Errorf("Compare(%s, %s) = %d", ordered[i], ordered[j], c)
+			}
+			if Equal(a, b) != (i == j) {
+				t.Errorf("Equal(%s, %s) = %v", ordered[i], ordered[j], !(i == j))
+			}
+		}
+	}
+
+	a := NewOrdered()
+	a.UseNumber()
+	if err := a.UnmarshalJSON([]byte(`{"b":[1.0,12345678901234567891],"a":"x"}`)); err != nil {
+		t.Fatal(err)
+	}
+	b := mustUnmarshal(t, `{"a":"x","b":[1,12345678901234567891]}`)
+	if !Equal(a, b) {
+		t.Error("ordered json.Number tree not equal to plain tree")
+	}
+	c := NewOrdered()
+	c.UseNumber()
+	if err := c.UnmarshalJSON([]byte(`{"a":"x","b":[1,12345678901234567892]}`)); err != nil {
+		t.Fatal(err)
+	}
+	if Equal(a, c) || Compare(a, c) != -1 {
+		t.Error("large integers compared inexactly")
+	}
+	if Equal(New(), New()) || Compare(New(), NewNull()) != -1 {
+		t.Error("uninitialized tree compared as a value")
+	}
+}
+
+func TestMarshalCanonical(t *testing.T) {
+	for _, test := range []struct{ in, expect string }{
+		{`{"b":1,"a":[true,null,"x"]}`, `{"a":[true,null,"x"],"b":1}`},
+		{`{"\u20ac":1,"\r":2,"1":3,"\ud83d\ude00":4,"\u0080":5,"\u00f6":6}`,
+			"{\"\\r\":2,\"1\":3,\"\u0080\":5,\"\u00f6\":6,\"\u20ac\":1,\"\U0001F600\":4}"},
+		{`"\u0007<>&\u2028\"\\\n"`, "\"\\u0007<>&\u2028\\\"\\\\\\n\""},
+		{`[0,-0,1.0,100,1e21,1e20,0.000001,1e-7,-1.5e-9,123.456,9007199254740993,5e-324,1.7976931348623157e308]`,
+			`[0,0,1,100,1e+21,100000000000000000000,0.000001,1e-7,-1.5e-9,123.456,9007199254740992,5e-324,1.7976931348623157e+308]`},
+	} {
+		for _, useNumber := range []bool{false, true} {
+			tree := NewOrdered()
+			if useNumber {
+				tree.UseNumber()
+			}
+			if err := tree.UnmarshalJSON([]byte(test.in)); err != nil {
+				t.Fatal(err)
+			}
+			p, err := tree.MarshalCanonical()
+			if err != nil {
+				t.Error(err)
+				continue
+			}
+			if string(p) != test.expect {
+				t.Errorf("canonical %s: %s != %s", test.in, p, test.expect)
+			}
+		}
+	}
+
+	a := mustUnmarshal(t, `{"x":{"b":2.0,"a":1}}`)
+	b := mustUnmarshal(t, `{"a":1,"b":2}`)
+	ha, err := a.Get("x").Hash(sha256.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hb, _ := b.Hash(sha256.New())
+	if !bytes.Equal(ha, hb) {
+		t.Error("equal values hashed differently")
+	}
+
+	big := New()
+	big.UseNumber()
+	if err := big.UnmarshalJSON([]byte(`[1e400]`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := big.MarshalCanonical(); err == nil {
+		t.Error("expected error for a number overflowing float64")
+	}
+}
+
+func TestPath(t *testing.T) {
+	tree := mustUnmarshal(t, `{"a.b":{"c[0]":[1,{"it's":true}]}}`)
+	node := tree.Get("a.b").Get("c[0]").GetIndex(1).Get("it's")
+	path := node.Path()
+	if s := path.String(); s != `$['a.b']['c[0]'][1]['it\'s']` {
+		t.Errorf("path string: %s", s)
+	}
+	if s := path.Pointer().String(); s != "/a.b/c[0]/1/it's" {
+		t.Errorf("path pointer: %s", s)
+	}
+	if len(tree.Path()) != 0 || tree.Path().String() != "$" {
+		t.Errorf("root path: %v", tree.Path())
+	}
+
+	parsed, err := ParsePath(path.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.String() != path.String() {
+		t.Errorf("parsed path: %s", parsed)
+	}
+	other := mustUnmarshal(t, `{"a.b":{"c[0]":[null,{"it's":"other"}]}}`)
+	if s, err := parsed.Lookup(other).String(); err != nil || s != "other" {
+		t.Errorf("lookup: %q %v", s, err)
+	}
+
+	parsed, err = ParsePath(`$.a["b\n"][2]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := Path{KeySegment("a"), KeySegment("b\n"), IndexSegment(2)}
+	if parsed.String() != expect.String() {
+		t.Errorf("parsed %s != %s", parsed, expect)
+	}
+	for _, bad := range []string{``, `a`, `$[`, `$['a'`, `$[-1]`, `$..a`, `$[x]`} {
+		if _, err := ParsePath(bad); err == nil {
+			t.Errorf("expected error parsing %q", bad)
+		}
+	}
+	if err := (Path{KeySegment("x")}).Lookup(other).Err(); err == nil {
+		t.Error("expected lookup error")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tree := mustUnmarshal(t, `{"a":[1],"s":"x"}`)
+	for _, test := range []struct {
+		err    error
+		target error
+	}{
+		{tree.Get("b").Err(), ErrNoExist},
+		{tree.Get("b").Get("c").Err(), ErrNoExist},
+		{tree.Get("a").GetIndex(1).Err(), ErrIndexOutOfRange},
+		{New().Get("a").Err(), ErrUninitialized},
+		{tree.Delete("b"), ErrNoExist},
+		{tree.Get("a").DeleteIndex(3), ErrIndexOutOfRange},
+		{New().Append(NewNull()), ErrUninitialized},
+		{tree.Pointer("/a/5").Err(), ErrIndexOutOfRange},
+		{func() error { _, err := New().Len(); return err }(), ErrUninitialized},
+		{func() error { _, err := tree.Get("b").Len(); return err }(), ErrNoExist},
+	} {
+		if !errors.Is(test.err, test.target) {
+			t.Errorf("%v is not %v", test.err, test.target)
+		}
+		var perr *PathError
+		if !errors.As(test.err, &perr) {
+			t.Errorf("%v is not a *PathError", test.err)
+		}
+	}
+
+	for _, err := range []error{
+		tree.Get("s").GetIndex(0).Err(),
+		tree.Get("s").Append(NewNull()),
+		func() error { _, err := tree.Get("s").Number(); return err }(),
+		func() error { _, err := tree.Get("a").Object(); return err }(),
+		func() error { _, err := tree.Get("s").Len(); return err }(),
+	} {
+		var terr *TypeError
+		if !errors.As(err, &terr) {
+			t.Errorf("%v is not a *TypeError", err)
+		}
+	}
+	_, err := tree.Get("a").String()
+	var terr *TypeError
+	if !errors.As(err, &terr) || terr.Expected != String || terr.Actual != Array {
+		t.Errorf("type error: %#v", terr)
+	}
+	if err.Error() != "not a string (array); $.a" {
+		t.Errorf("error message: %v", err)
+	}
+}
+
+func TestEach(t *testing.T) {
+	tree := NewOrdered()
+	if err := tree.UnmarshalJSON([]byte(`{"z":[1,2],"a":{"b":true}}`)); err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	err := tree.Each(func(key string, index int, child *JsonTree) error {
+		paths = append(paths, child.Path().String())
+		if index != -1 || child.Parent() != tree {
+			t.Errorf("member %q: index %d parent %v", key, index, child.Parent())
+		}
+		return child.Each(func(key string, index int, child *JsonTree) error {
+			paths = append(paths, child.Path().String())
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `$['z'] $['z'][0] $['z'][1] $['a'] $['a']['b']`
+	if s := strings.Join(paths, " "); s != expect {
+		t.Errorf("paths: %s", s)
+	}
+
+	stop := errors.New("stop")
+	var n int
+	err = tree.Get("z").Each(func(key string, index int, child *JsonTree) error {
+		n++
+		return stop
+	})
+	if err != stop || n != 1 {
+		t.Errorf("stop: %v %d", err, n)
+	}
+
+	children, err := tree.Get("z").Children()
+	if err != nil || len(children) != 2 {
+		t.Fatalf("children: %v %v", children, err)
+	}
+	if x, _ := children[1].Number(); x != 2 || children[1].JSONPointer() != "/z/1" {
+		t.Errorf("child: %v %s", x, children[1].JSONPointer())
+	}
+	_, err = tree.Get("a").Get("b").Children()
+	var perr *PathError
+	var terr *TypeError
+	if !errors.As(err, &perr) || perr.Path != "$.a.b" {
+		t.Errorf("boolean path error: %v", err)
+	}
+	if !errors.As(err, &terr) || terr.Expected != Object || terr.Actual != Boolean {
+		t.Errorf("boolean type error: %v", err)
+	}
+	if err := tree.Get("x").Each(nil); !errors.Is(err, ErrNoExist) {
+		t.Errorf("each error: %v", err)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	tree := NewOrdered()
+	if err := tree.UnmarshalJSON([]byte(`{"a":[1,{"secret":"x"}],"secret":"y","b":{"c":true}}`)); err != nil {
+		t.Fatal(err)
+	}
+	var visited []string
+	Walk(tree, func(node *JsonTree) WalkAction {
+		visited = append(visited, fmt.Sprintf("%d%s", node.Depth(), node.JSONPointer()))
+		if node.Path().String() == "$['a']" {
+			return SkipChildren
+		}
+		return Continue
+	})
+	expect := "0 1/a 1/secret 1/b 2/b/c"
+	if s := strings.Join(visited, " "); s != expect {
+		t.Errorf("pre-order: %s", s)
+	}
+
+	Walk(tree, func(node *JsonTree) WalkAction {
+		path := node.Path()
+		if len(path) > 0 && path[len(path)-1].Key == "secret" {
+			node.SetValue(NewString("***"))
+		}
+		return Continue
+	})
+	testMarshal(t, tree, `{"a":[1,{"secret":"***"}],"secret":"***","b":{"c":true}}`)
+
+	visited = nil
+	completed := WalkPostOrder(tree, func(node *JsonTree) WalkAction {
+		visited = append(visited, node.JSONPointer())
+		if node.JSONPointer() == "/secret" {
+			return Stop
+		}
+		return Continue
+	})
+	expect = "/a/0 /a/1/secret /a/1 /a /secret"
+	if s := strings.Join(visited, " "); s != expect || completed {
+		t.Errorf("post-order: %s (completed %v)", s, completed)
+	}
+
+	corrupt := NewLazy()
+	if err := corrupt.UnmarshalJSON([]byte(`[1, 2]`)); err != nil {
+		t.Fatal(err)
+	}
+	corrupt.val.(*lazyValue).raw = []byte(`[1, 2x]`)
+	var errs []error
+	Walk(corrupt, func(node *JsonTree) WalkAction {
+		errs = append(errs, node.Err())
+		return Continue
+	})
+	if len(errs) != 1 || errs[0] == nil || corrupt.Type() != Error {
+		t.Errorf("corrupt walk: %v", errs)
+	}
+}
+
+type decodeBase struct {
+	ID   int64 `json:"id"`
+	Name string
+}
+
+type decodeItem struct {
+	decodeBase
+	Price  float32           `json:"price"`
+	Tags   []string          `json:"tags,omitempty"`
+	Attrs  map[string]int    `json:"attrs"`
+	Extra  interface{}       `json:"extra"`
+	Raw    json.RawMessage   `json:"raw"`
+	Tree   *JsonTree         `json:"tree"`
+	Big    json.Number       `json:"big"`
+	Skip   string            `json:"-"`
+	Counts map[int]uint8     `json:"counts"`
+	Pair   [2]bool           `json:"pair"`
+	Ptr    *string           `json:"ptr"`
+	Keep   map[string]string `json:"keep"`
+}
+
+func TestDecode(t *testing.T) {
+	tree := NewOrdered()
+	tree.UseNumber()
+	err := tree.UnmarshalJSON([]byte(`{"items":[{
+		"id": 12345678901234567,
+		"name": "widget",
+		"price": 2.5,
+		"tags": ["a", "b"],
+		"attrs": {"x": 1},
+		"extra": {"z": [1, null], "a": true},
+		"raw": {"b": 1, "a": 2},
+		"tree": {"k": [1]},
+		"big": 1e400,
+		"Skip": "no",
+		"counts": {"3": 4},
+		"pair": [true],
+		"ptr": null,
+		"keep": null,
+		"unknown": 1
+	}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var items []decodeItem
+	if err := tree.Get("items").Decode(&items); err != nil {
+		t.Fatal(err)
+	}
+	item := items[0]
+	if item.ID != 12345678901234567 || item.Name != "widget" {
+		t.Errorf("embedded: %+v", item.decodeBase)
+	}
+	if item.Price != 2.5 || len(item.Tags) != 2 || item.Attrs["x"] != 1 || item.Skip != "" {
+		t.Errorf("fields: %+v", item)
+	}
+	if p, _ := json.Marshal(item.Extra); string(p) != `{"a":true,"z":[1,null]}` {
+		t.Errorf("extra: %s", p)
+	}
+	if _, ok := item.Extra.(map[string]interface{})["z"].([]interface{})[0].(json.Number); !ok {
+		t.Errorf("extra number: %#v", item.Extra)
+	}
+	if string(item.Raw) != `{"b":1,"a":2}` {
+		t.Errorf("raw: %s", item.Raw)
+	}
+	if item.Tree == nil || item.Tree.Parent() != nil || item.Tree.Get("k").GetIndex(0).Err() != nil {
+		t.Errorf("tree: %v", item.Tree)
+	}
+	if item.Big != "1e400" || item.Counts[3] != 4 || item.Pair != [2]bool{true, false} {
+		t.Errorf("values: %v %v %v", item.Big, item.Counts, item.Pair)
+	}
+	if item.Ptr != nil || item.Keep != nil {
+		t.Errorf("nulls: %v %v", item.Ptr, item.Keep)
+	}
+
+	for _, test := range []struct {
+		json   string
+		expect string
+	}{
+		{`{"items":[{},{},{},{"price":"9"}]}`, "not a number (string); $.items[3].price"},
+		{`{"items":[{"id":1.5}]}`, "number 1.5 is not an integer; $.items[0].id"},
+		{`{"items":[{"counts":{"1":300}}]}`, "number 300 overflows uint8; $.items[0].counts.1"},
+		{`{"items":[{"counts":{"x":1}}]}`, "invalid int key \"x\"; $.items[0].counts.x"},
+		{`{"items":{"tags":[]}}`, "not an array (object); $.items"},
+	} {
+		var items []decodeItem
+		err := mustUnmarshal(t, test.json).Get("items").Decode(&items)
+		if err == nil || err.Error() != test.expect {
+			t.Errorf("decode %s: %v", test.json, err)
+		}
+		var perr *PathError
+		if !errors.As(err, &perr) {
+			t.Errorf("decode %s: not a *PathError", test.json)
+		}
+	}
+
+	var item2 decodeItem
+	if err := tree.Get("items").Decode(item2); err == nil {
+		t.Error("expected error for a non-pointer")
+	}
+}
+
+type nullUnmarshaler struct{ input string }
+
+func (u *nullUnmarshaler) UnmarshalJSON(p []byte) error {
+	u.input = string(p)
+	return nil
+}
+
+func TestDecodeNull(t *testing.T) {
+	type nulls struct {
+		Value nullUnmarshaler  `json:"value"`
+		Ptr   *nullUnmarshaler `json:"ptr"`
+		Raw   json.RawMessage  `json:"raw"`
+		Tree  *JsonTree        `json:"tree"`
+	}
+	input := `{"value":null,"ptr":null,"raw":null,"tree":null}`
+	var expect, decoded nulls
+	expect.Ptr, decoded.Ptr = &nullUnmarshaler{}, &nullUnmarshaler{}
+	if err := json.Unmarshal([]byte(input), &expect); err != nil {
+		t.Fatal(err)
+	}
+	if err := mustUnmarshal(t, input).Decode(&decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Value.input != "null" || decoded.Ptr != nil || string(decoded.Raw) != "null" || decoded.Tree != nil {
+		t.Errorf("decoded: %+v", decoded)
+	}
+	if decoded.Value != expect.Value || decoded.Ptr != expect.Ptr || string(decoded.Raw) != string(expect.Raw) {
+		t.Errorf("decoded %+v, json.Unmarshal %+v", decoded, expect)
+	}
+}
+
+type dupFirst struct {
+	Name string
+	X    int
+}
+
+type dupSecond struct {
+	Name string
+	Y    int `json:"X"`
+}
+
+type decodeFields struct {
+	dupFirst
+	dupSecond
+	Count int   `json:"count,string"`
+	OK    *bool `json:"ok,string"`
+}
+
+func TestDecodeFields(t *testing.T) {
+	input := `{"Name":"n","X":5,"count":"12","ok":"true"}`
+	var expect, fields decodeFields
+	if err := json.Unmarshal([]byte(input), &expect); err != nil {
+		t.Fatal(err)
+	}
+	if err := mustUnmarshal(t, input).Decode(&fields); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fields, expect) {
+		t.Errorf("decode: %+v != %+v", fields, expect)
+	}
+	if fields.dupFirst.Name != "" || fields.X != 0 || fields.Y != 5 || fields.Count != 12 || !*fields.OK {
+		t.Errorf("fields: %+v", fields)
+	}
+
+	err := mustUnmarshal(t, `{"count":12}`).Decode(&fields)
+	if err == nil || err.Error() != "invalid use of ,string struct tag, trying to decode unquoted value into int; $.count" {
+		t.Errorf("unquoted: %v", err)
+	}
+
+	tree, err := FromValue(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testMarshal(t, tree, `{"X":5,"count":"12","ok":"true"}`)
+}
+
+type valueMarshaler struct{ n int }
+
+func (m *valueMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`{"n":%d}`, m.n)), nil
+}
+
+var errMarshal = errors.New("marshal failed")
+
+type failingMarshaler struct{}
+
+func (failingMarshaler) MarshalJSON() ([]byte, error) {
+	return nil, errMarshal
+}
+
+func TestFromValue(t *testing.T) {
+	type inner struct {
+		When time.Time `json:"when"`
+	}
+	type item struct {
+		inner
+		ID      uint64            `json:"id"`
+		Name    string            `json:"name,omitempty"`
+		Price   float32           `json:"price"`
+		Tags    []string          `json:"tags"`
+		Counts  map[int]int8      `json:"counts"`
+		Data    []byte            `json:"data"`
+		M       valueMarshaler    `json:"m"`
+		Tree    *JsonTree         `json:"tree"`
+		Any     interface{}       `json:"any"`
+		Nil     *int              `json:"nil"`
+		Ignored string            `json:"-"`
+		hidden  string            // unexported fields are skipped
+		Empty   map[string]string `json:"empty,omitempty"`
+	}
+	when := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	tree, err := FromValue([]item{{
+		inner:  inner{When: when},
+		ID:     18446744073709551615,
+		Price:  0.1,
+		Tags:   []string{"a"},
+		Counts: map[int]int8{2: -1},
+		Data:   []byte("hi"),
+		M:      valueMarshaler{3},
+		Tree:   mustUnmarshal(t, `{"x":[true]}`),
+		Any:    map[string]interface{}{"y": []int{1}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testMarshal(t, tree, `[{"any":{"y":[1]},"counts":{"2":-1},"data":"aGk=","id":18446744073709551615,`+
+		`"m":{"n":3},"nil":null,"price":0.1,"tags":["a"],"tree":{"x":[true]},"when":"2026-10-17T12:00:00Z"}]`)
+	if x, err := tree.GetIndex(0).Get("id").Uint64(); err != nil || x != 18446744073709551615 {
+		t.Errorf("id: %v %v", x, err)
+	}
+	if tree.GetIndex(0).Get("tree").Type() != Object {
+		t.Errorf("tree type: %v", tree.GetIndex(0).Get("tree").Type())
+	}
+
+	var back []item
+	if err := tree.Decode(&back); err != nil {
+		t.Fatal(err)
+	}
+	if !back[0].When.Equal(when) || string(back[0].Data) != "hi" || back[0].Counts[2] != -1 {
+		t.Errorf("round trip: %+v", back[0])
+	}
+
+	for _, test := range []struct {
+		v      interface{}
+		expect string
+	}{
+		{map[string]interface{}{"a": []interface{}{1, make(chan int)}}, "unsupported type chan int; $.a[1]"},
+		{struct{ F func() }{}, "unsupported type func(); $.F"},
+		{[]float64{math.NaN()}, "invalid number NaN; $[0]"},
+		{map[[2]int]bool{{1, 2}: true}, "unsupported map key type [2]int; $"},
+	} {
+		_, err := FromValue(test.v)
+		if err == nil || err.Error() != test.expect {
+			t.Errorf("FromValue(%#v): %v", test.v, err)
+		}
+	}
+
+	_, err = FromValue(map[string]interface{}{"f": failingMarshaler{}})
+	if !errors.Is(err, errMarshal) || err.Error() != "marshal failed; $.f" {
+		t.Errorf("marshaler error: %v", err)
+	}
+
+	type node struct{ Next *node }
+	cycle := &node{}
+	cycle.Next = cycle
+	if _, err := FromValue(cycle); err == nil {
+		t.Error("expected error for a cycle")
+	}
+}
+
+func TestPositions(t *testing.T) {
+	src := "{\n  \"servers\": [\n    {\"port\": 80},\n    {\"port\": \"x\"}\n  ],\n\t\"name\" :\"a\"\n}"
+	tree := NewOrdered()
+	tree.TrackPositions(Position{Filename: "config.json"})
+	if err := tree.UnmarshalJSON([]byte(src)); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		node  *JsonTree
+		value string
+		key   string
+	}{
+		{tree, "config.json:1:1", "-"},
+		{tree.Get("servers"), "config.json:2:14", "config.json:2:3"},
+		{tree.Get("servers").GetIndex(1), "config.json:4:5", "-"},
+		{tree.Get("servers").GetIndex(1).Get("port"), "config.json:4:14", "config.json:4:6"},
+		{tree.Get("name"), "config.json:6:10", "config.json:6:2"},
+	} {
+		if s := test.node.Position().String(); s != test.value {
+			t.Errorf("%s: position %s != %s", test.node.path(), s, test.value)
+		}
+		if s := test.node.KeyPosition().String(); s != test.key {
+			t.Errorf("%s: key position %s != %s", test.node.path(), s, test.key)
+		}
+	}
+	if off := tree.Get("name").Position().Offset; src[off] != '"' || src[off+1:off+3] != `a"` {
+		t.Errorf("offset %d", off)
+	}
+
+	_, err := tree.Get("servers").GetIndex(1).Get("port").Number()
+	if err == nil || err.Error() != "config.json:4:14: not a number (string); $.servers[1].port" {
+		t.Errorf("error: %v", err)
+	}
+	err = tree.Get("servers").GetIndex(0).Get("host").Err()
+	if err == nil || err.Error() != "config.json:3:5: key does not exist; $.servers[0].host" {
+		t.Errorf("missing key error: %v", err)
+	}
+
+	offset := New()
+	offset.TrackPositions(Position{Offset: 100, Line: 10, Column: 5})
+	if err := offset.UnmarshalJSON([]byte("[1,\n 2]")); err != nil {
+		t.Fatal(err)
+	}
+	if pos := offset.GetIndex(0).Position(); pos.String() != "10:6" || pos.Offset != 101 {
+		t.Errorf("first line: %v %d", pos, pos.Offset)
+	}
+	if pos := offset.GetIndex(1).Position(); pos.String() != "11:2" || pos.Offset != 105 {
+		t.Errorf("second line: %v %d", pos, pos.Offset)
+	}
+
+	if mustUnmarshal(t, `{"a":1}`).Get("a").Position().IsValid() {
+		t.Error("position without tracking")
+	}
+}
+
+func TestLazy(t *testing.T) {
+	src := `{"a": [1, {"b": "xé"}, true],  "c": {"d": 1.50, "e": null}, "f": "g"}`
+	tree := NewLazy()
+	if err := tree.UnmarshalJSON([]byte(src)); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := tree.Get("a").Len(); err != nil || n != 3 {
+		t.Errorf("length %d %v", n, err)
+	}
+	if s, err := tree.Get("a").GetIndex(1).Get("b").String(); err != nil || s != "xé" {
+		t.Errorf("string %q %v", s, err)
+	}
+	if x, err := tree.Get("c").Get("d").Number(); err != nil || x != 1.5 {
+		t.Errorf("number %v %v", x, err)
+	}
+	if err := tree.Get("a").GetIndex(3).Err(); !errors.Is(err, ErrIndexOutOfRange) {
+		t.Errorf("index error: %v", err)
+	}
+	if tree.Get("c").Type() != Object || !tree.Get("c").Get("e").IsNull() {
+		t.Errorf("types")
+	}
+	if p, err := tree.MarshalJSON(); err != nil || string(p) != src {
+		t.Errorf("untouched marshal: %s %v", p, err)
+	}
+	if !Equal(tree, mustUnmarshal(t, src)) {
+		t.Errorf("not equal to eagerly decoded tree")
+	}
+
+	tree = NewLazy()
+	if err := tree.UnmarshalJSON([]byte(src)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Get("c").Set("e", NewString("h")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Get("a").Append(NewBoolean(false)); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"a":[1,{"b":"xé"},true,false],"c":{"d":1.5,"e":"h"},"f":"g"}`
+	if p, err := tree.MarshalJSON(); err != nil || string(p) != want {
+		t.Errorf("modified marshal: %s %v", p, err)
+	}
+	if s, err := tree.Get("c").Get("e").String(); err != nil || s != "h" {
+		t.Errorf("modified value %q %v", s, err)
+	}
+
+	escaped := NewLazy()
+	if err := escaped.UnmarshalJSON([]byte(`{"q\\\"": "\\", "r": ["]\"", 2]}`)); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := escaped.Get(`q\"`).String(); err != nil || s != `\` {
+		t.Errorf("escaped key %q %v", s, err)
+	}
+	if x, err := escaped.Get("r").GetIndex(1).Number(); err != nil || x != 2 {
+		t.Errorf("element after escaped string %v %v", x, err)
+	}
+
+	for _, bad := range []string{`{"a":[1,}`, `[1e400]`} {
+		if err := NewLazy().UnmarshalJSON([]byte(bad)); err == nil {
+			t.Errorf("no error for %s", bad)
+		}
+	}
+
+	numbers := NewLazy()
+	numbers.UseNumber()
+	if err := numbers.UnmarshalJSON([]byte(src)); err != nil {
+		t.Fatal(err)
+	}
+	if err := numbers.Get("c").Delete("e"); err != nil {
+		t.Fatal(err)
+	}
+	testMarshal(t, numbers, `{"a":[1, {"b": "xé"}, true],"c":{"d":1.50},"f":"g"}`)
+
+	corrupt := NewLazy()
+	if err := corrupt.UnmarshalJSON([]byte(`[1, 2]`)); err != nil {
+		t.Fatal(err)
+	}
+	corrupt.val.(*lazyValue).raw = []byte(`[1, 2x]`)
+	var perr *PathError
+	if _, err := corrupt.Array(); !errors.As(err, &perr) || corrupt.Err() != err {
+		t.Errorf("decode error: %v", err)
+	}
+}
+
+func TestLazyRead(t *testing.T) {
+	src := `{"b": 1.50, "a": {"x": [1.0, 2e0]}, "c": [ {"d": 1.0} ]}`
+	tree := NewLazy()
+	if err := tree.UnmarshalJSON([]byte(src)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tree.Keys(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Get("a").Each(func(string, int, *JsonTree) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	Walk(tree, func(*JsonTree) WalkAction { return Continue })
+	if _, err := tree.Object(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tree.Get("c").Array(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tree.Get("c").GetIndex(0).Interface(); err != nil {
+		t.Fatal(err)
+	}
+	testMarshal(t, tree, src)
+	testMarshal(t, tree.Copy(), src)
+	testMarshal(t, tree.Get("a"), `{"x": [1.0, 2e0]}`)
+
+	if err := tree.Get("c").GetIndex(0).Set("e", NewBoolean(true)); err != nil {
+		t.Fatal(err)
+	}
+	testMarshal(t, tree, `{"b":1.50,"a":{"x": [1.0, 2e0]},"c":[{"d":1,"e":true}]}`)
+	if err := tree.Get("a").Get("x").Append(NewNull()); err != nil {
+		t.Fatal(err)
+	}
+	testMarshal(t, tree, `{"b":1.50,"a":{"x":[1,2,null]},"c":[{"d":1,"e":true}]}`)
+}
+
+func TestLazyMergePatch(t *testing.T) {
+	src := `{"a": {"b": 1, "c": [2]}, "d": "e"}`
+	tree := NewLazy()
+	if err := tree.UnmarshalJSON([]byte(src)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.MergePatch(mustUnmarshal(t, `{"a": {"b": null, "f": true}}`)); err != nil {
+		t.Fatal(err)
+	}
+	testMarshal(t, tree, `{"a":{"c":[2],"f":true},"d":"e"}`)
+
+	tree = NewLazy()
+	if err := tree.UnmarshalJSON([]byte(src)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Get("a").MergePatch(mustUnmarshal(t, `{"c": null, "g": 3}`)); err != nil {
+		t.Fatal(err)
+	}
+	testMarshal(t, tree, `{"a":{"b":1,"g":3},"d":"e"}`)
+}
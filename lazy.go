@@ -0,0 +1,525 @@
+// Copyright 2013, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// lazy.go [created: Sat, 17 Oct 2026]
+
+package jsontree
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// creates an empty *JsonTree that keeps the raw bytes given to UnmarshalJSON
+// and decodes arrays and objects only when their contents are needed. Get,
+// GetIndex and Len scan just enough of a container to find its children, so
+// reading a few values from a large document decodes little else. other
+// methods decode the whole subtree they are called on. MarshalJSON writes
+// subtrees that have not been modified byte for byte, whether or not they
+// have been decoded, and decoded objects keep their keys in input order, as
+// with NewOrdered. only containers that are modified are written from their
+// decoded values, so their numbers lose the formatting of the input (1.50 is
+// written as 1.5) unless UseNumber is called. the input is validated by
+// UnmarshalJSON. positions are not tracked for lazy trees.
+//
+// because reading a lazy tree decodes parts of it, a lazy tree is not safe
+// for concurrent use, even by readers, without synchronization.
+func NewLazy() *JsonTree {
+	tree := NewOrdered()
+	tree.lazy = true
+	return tree
+}
+
+// an array or object that is decoded when needed.
+type lazyValue struct {
+	raw       []byte
+	ordered   bool
+	useNumber bool
+
+	indexed  bool
+	children []lazyChild    // in input order
+	members  map[string]int // the index in children of each key of an object
+
+	loaded bool
+	val    interface{}
+	err    error // the error decoding raw, if loaded
+	dirty  bool  // val was modified or replaced, and is not read through the index
+}
+
+// a member or element of an indexed container. once touched val holds a
+// *lazyValue for raw, or the value that replaced it.
+type lazyChild struct {
+	key     string
+	raw     []byte
+	touched bool
+	val     interface{}
+}
+
+// keeps p, a JSON array or object, to be decoded when needed.
+func (tree *JsonTree) unmarshalLazy(p []byte) error {
+	if !json.Valid(p) {
+		var v interface{}
+		return json.Unmarshal(p, &v)
+	}
+	if !tree.useNumber {
+		if err := checkFloats(p); err != nil {
+			return err
+		}
+	}
+	raw := bytes.TrimSpace(p)
+	tree.val = &lazyValue{
+		raw:       append([]byte(nil), raw...),
+		ordered:   tree.ordered,
+		useNumber: tree.useNumber,
+	}
+	return nil
+}
+
+// reports whether p holds an array or an object.
+func isContainer(p []byte) bool {
+	p = bytes.TrimSpace(p)
+	return len(p) > 0 && (p[0] == '{' || p[0] == '[')
+}
+
+// returns an error if a number in the valid JSON p can not be decoded as a
+// float64. only numbers with exponents or many digits are checked.
+func checkFloats(p []byte) error {
+	for i := 0; i < len(p); i++ {
+		switch c := p[i]; {
+		case c == '"':
+			i = scanString(p, i) - 1
+		case c == '-' || ('0' <= c && c <= '9'):
+			j := scanValue(p, i)
+			if j-i > 300 || bytes.IndexAny(p[i:j], "eE") >= 0 {
+				var v interface{}
+				if err := json.Unmarshal(p[i:j], &v); err != nil {
+					return err
+				}
+			}
+			i = j - 1
+		}
+	}
+	return nil
+}
+
+// replaces a lazy value of tree with its decoded value, keeping the lazy
+// value as the source of tree so that it can still be written as it appears in
+// the input. if the value can not be decoded the error is stored on tree and
+// returned.
+func (tree *JsonTree) load() error {
+	lv, ok := tree.val.(*lazyValue)
+	if !ok {
+		return nil
+	}
+	val, err := lv.load()
+	if err != nil {
+		tree.setError(err)
+		tree.getType()
+		return *tree.err
+	}
+	tree.val = val
+	tree.source = lv
+	return nil
+}
+
+// the value of tree to find children in. children of a decoded container are
+// still found through its index, if it has one, so that they share their
+// lazy values with the container.
+func (tree *JsonTree) container() interface{} {
+	if tree.source != nil && tree.source.indexed {
+		return tree.source
+	}
+	return tree.val
+}
+
+// marks the value of tree as modified in place. the change is recorded on
+// the lazy value tree, or its nearest ancestor, was decoded from.
+func (tree *JsonTree) changed() {
+	for t := tree; t != nil; t = t.parent {
+		if t.source != nil {
+			t.source.setDirty()
+			return
+		}
+	}
+}
+
+// decodes the value of tree if it is a lazy scalar. arrays and objects are
+// decoded when they are needed.
+func (tree *JsonTree) loadScalar() {
+	if lv, ok := tree.val.(*lazyValue); ok && !lv.isContainer() {
+		tree.load()
+	}
+}
+
+// the member key of the object val, which may be lazy.
+func objectMember(val interface{}, key string) (interface{}, bool) {
+	if lv, ok := val.(*lazyValue); ok && (lv.indexed || !lv.decoded()) {
+		lv.index()
+		i, ok := lv.members[key]
+		if !ok {
+			return nil, false
+		}
+		return lv.touch(i), true
+	}
+	val, _ = loadValue(val)
+	val, ok := objectMap(val)[key]
+	return val, ok
+}
+
+// the i-th element of the array val, which may be lazy.
+func arrayElem(val interface{}, i int) (interface{}, bool) {
+	if lv, ok := val.(*lazyValue); ok && (lv.indexed || !lv.decoded()) {
+		lv.index()
+		if i < 0 || len(lv.children) <= i {
+			return nil, false
+		}
+		return lv.touch(i), true
+	}
+	val, _ = loadValue(val)
+	a := val.([]interface{})
+	if i < 0 || len(a) <= i {
+		return nil, false
+	}
+	return a[i], true
+}
+
+// the number of members or elements in the container val, which may be lazy.
+func containerLen(val interface{}) int {
+	if lv, ok := val.(*lazyValue); ok && (lv.indexed || !lv.decoded()) {
+		lv.index()
+		if lv.members != nil {
+			return len(lv.members)
+		}
+		return len(lv.children)
+	}
+	val, _ = loadValue(val)
+	switch val := val.(type) {
+	case []interface{}:
+		return len(val)
+	default:
+		return len(objectMap(val))
+	}
+}
+
+// reports whether lv has been decoded without error. containers that could
+// not be decoded are still read through their index.
+func (lv *lazyValue) decoded() bool {
+	return lv.loaded && lv.err == nil
+}
+
+func (lv *lazyValue) isContainer() bool {
+	return lv.raw[0] == '{' || lv.raw[0] == '['
+}
+
+func (lv *lazyValue) jsonType() JsonType {
+	if lv.raw[0] == '{' {
+		return Object
+	}
+	return Array
+}
+
+// finds the children of lv without decoding them.
+func (lv *lazyValue) index() {
+	if lv.indexed {
+		return
+	}
+	lv.indexed = true
+	p := lv.raw
+	if p[0] == '{' {
+		lv.members = make(map[string]int)
+	}
+	for i := skipSpace(p, 1); p[i] != '}' && p[i] != ']'; {
+		var key string
+		if lv.members != nil {
+			end := scanString(p, i)
+			key = unquote(p[i:end])
+			lv.members[key] = len(lv.children)
+			i = skipSpace(p, skipSpace(p, end)+1)
+		}
+		end := scanValue(p, i)
+		lv.children = append(lv.children, lazyChild{key: key, raw: p[i:end]})
+		if i = skipSpace(p, end); p[i] == ',' {
+			i = skipSpace(p, i+1)
+		}
+	}
+}
+
+// the value of the i-th child of the indexed container lv.
+func (lv *lazyValue) touch(i int) interface{} {
+	c := &lv.children[i]
+	if !c.touched {
+		c.val = lv.child(c.raw)
+		c.touched = true
+	}
+	return c.val
+}
+
+func (lv *lazyValue) child(raw []byte) *lazyValue {
+	return &lazyValue{raw: raw, ordered: lv.ordered, useNumber: lv.useNumber}
+}
+
+// the decoded value of lv. decoded arrays and objects are shared by every
+// tree that refers to lv.
+func (lv *lazyValue) load() (interface{}, error) {
+	if !lv.loaded {
+		lv.val, lv.err = lv.decode()
+		lv.loaded = true
+	}
+	return lv.val, lv.err
+}
+
+func (lv *lazyValue) decode() (interface{}, error) {
+	p := lv.raw
+	switch p[0] {
+	case '{', '[':
+		lv.index()
+		return lv.decodeIndexed()
+	case '"':
+		return unquote(p), nil
+	case 't':
+		return true, nil
+	case 'f':
+		return false, nil
+	case 'n':
+		return nil, nil
+	}
+	if lv.useNumber {
+		return json.Number(p), nil
+	}
+	return strconv.ParseFloat(string(p), 64)
+}
+
+// builds the value of an indexed container from its children, some of which
+// may have been decoded or replaced. the children keep their lazy values, so
+// that they can still be written as they appear in the input.
+func (lv *lazyValue) decodeIndexed() (interface{}, error) {
+	if lv.members == nil {
+		a := make([]interface{}, len(lv.children))
+		for i := range lv.children {
+			val, err := loadValue(lv.touch(i))
+			if err != nil {
+				return nil, err
+			}
+			a[i] = val
+		}
+		return a, nil
+	}
+	var obj interface{} = make(map[string]interface{}, len(lv.members))
+	if lv.ordered {
+		obj = &OrderedObject{m: make(map[string]interface{}, len(lv.members))}
+	}
+	for i := range lv.children {
+		val, err := loadValue(lv.touch(i))
+		if err != nil {
+			return nil, err
+		}
+		objectSet(obj, lv.children[i].key, val)
+	}
+	return obj, nil
+}
+
+func loadValue(val interface{}) (interface{}, error) {
+	if lv, ok := val.(*lazyValue); ok {
+		return lv.load()
+	}
+	return val, nil
+}
+
+// marks the decoded value of lv as modified. its index is dropped because
+// the value may no longer match it.
+func (lv *lazyValue) setDirty() {
+	lv.dirty = true
+	lv.indexed = false
+	lv.children = nil
+	lv.members = nil
+}
+
+// replaces the decoded value of lv with val.
+func (lv *lazyValue) replace(val interface{}) {
+	lv.val = val
+	lv.loaded = true
+	lv.err = nil
+	lv.setDirty()
+}
+
+// replaces the member key or element i of the container lv with val.
+func (lv *lazyValue) set(key string, i int, val interface{}) {
+	if lv.loaded {
+		switch pval := lv.val.(type) {
+		case []interface{}:
+			if 0 <= i && i < len(pval) {
+				pval[i] = val
+			}
+		case map[string]interface{}, *OrderedObject:
+			if i < 0 {
+				objectSet(pval, key, val)
+			}
+		}
+	}
+	if !lv.indexed {
+		if lv.loaded {
+			lv.setDirty()
+		}
+		return
+	}
+	if lv.members != nil {
+		var ok bool
+		if i, ok = lv.members[key]; !ok {
+			return
+		}
+	}
+	if 0 <= i && i < len(lv.children) {
+		lv.children[i].val = val
+		lv.children[i].touched = true
+	}
+}
+
+// reports whether lv may no longer match its raw bytes.
+func (lv *lazyValue) modified() bool {
+	if lv.dirty {
+		return true
+	}
+	for _, c := range lv.children {
+		if !c.touched {
+			continue
+		}
+		if child, ok := c.val.(*lazyValue); !ok || child.modified() {
+			return true
+		}
+	}
+	return false
+}
+
+// implements json.Marshaler. parts of lv that have not been modified are
+// written as they appear in the input.
+func (lv *lazyValue) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := lv.marshal(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (lv *lazyValue) marshal(buf *bytes.Buffer) error {
+	if !lv.modified() {
+		buf.Write(lv.raw)
+		return nil
+	}
+	if lv.dirty {
+		p, err := json.Marshal(lv.val)
+		buf.Write(p)
+		return err
+	}
+	openDelim, closeDelim := byte('['), byte(']')
+	if lv.members != nil {
+		openDelim, closeDelim = '{', '}'
+	}
+	buf.WriteByte(openDelim)
+	for i, c := range lv.children {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if lv.members != nil {
+			p, err := json.Marshal(c.key)
+			if err != nil {
+				return err
+			}
+			buf.Write(p)
+			buf.WriteByte(':')
+		}
+		var err error
+		switch val := c.val.(type) {
+		case *lazyValue:
+			err = val.marshal(buf)
+		default:
+			if !c.touched {
+				buf.Write(c.raw)
+				continue
+			}
+			var p []byte
+			p, err = json.Marshal(val)
+			buf.Write(p)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	buf.WriteByte(closeDelim)
+	return nil
+}
+
+// a copy of lv that shares its raw bytes if they are still current.
+func (lv *lazyValue) copy() interface{} {
+	if lv.modified() {
+		if val, err := lv.load(); err == nil {
+			return copyValue(val)
+		}
+	}
+	return &lazyValue{raw: lv.raw, ordered: lv.ordered, useNumber: lv.useNumber}
+}
+
+// the string value of the valid JSON string p.
+func unquote(p []byte) string {
+	s := p[1 : len(p)-1]
+	for _, c := range s {
+		if c == '\\' || c >= utf8.RuneSelf {
+			var str string
+			json.Unmarshal(p, &str)
+			return str
+		}
+	}
+	return string(s)
+}
+
+// the offset of the first byte at or after i in p that is not whitespace.
+func skipSpace(p []byte, i int) int {
+	for i < len(p) && strings.IndexByte(" \t\r\n", p[i]) >= 0 {
+		i++
+	}
+	return i
+}
+
+// the offset just past the string beginning at p[i].
+func scanString(p []byte, i int) int {
+	for i++; ; i++ {
+		i += bytes.IndexByte(p[i:], '"')
+		n := 0
+		for p[i-n-1] == '\\' {
+			n++
+		}
+		if n%2 == 0 {
+			return i + 1
+		}
+	}
+}
+
+// the offset just past the value beginning at p[i]. p must be valid JSON.
+func scanValue(p []byte, i int) int {
+	switch p[i] {
+	case '"':
+		return scanString(p, i)
+	case '{', '[':
+		depth := 0
+		for ; ; i++ {
+			switch p[i] {
+			case '"':
+				i = scanString(p, i) - 1
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return i + 1
+				}
+			}
+		}
+	}
+	for i < len(p) && strings.IndexByte(",]} \t\r\n", p[i]) < 0 {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,114 @@
+// Copyright 2013, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// merge.go [created: Sat, 17 Oct 2026]
+
+package jsontree
+
+// applies the JSON Merge Patch (RFC 7396) patch to tree. members of patch
+// objects are merged recursively into tree, replacing values that are not
+// objects, and null members remove keys from tree. changes are written
+// through to the tree's parent so they are visible from Root(). returns a
+// *PathError if tree or patch is uninitialized or has an error.
+func (tree *JsonTree) MergePatch(patch *JsonTree) error {
+	pval, err := patch.value()
+	if err != nil {
+		return err
+	}
+	tval, err := tree.value()
+	if err != nil {
+		return err
+	}
+	tree.setVal(mergePatch(tval, pval, tree.ordered))
+	tree.getType()
+	return nil
+}
+
+// objects created in target are ordered if ordered is true.
+func mergePatch(target, patch interface{}, ordered bool) interface{} {
+	if !isObject(patch) {
+		return copyValue(patch)
+	}
+	if !isObject(target) {
+		target = newObject(ordered)
+	}
+	pmap := objectMap(patch)
+	tmap := objectMap(target)
+	for _, k := range objectKeys(patch) {
+		if pmap[k] == nil {
+			objectDelete(target, k)
+			continue
+		}
+		objectSet(target, k, mergePatch(tmap[k], pmap[k], ordered))
+	}
+	return target
+}
+
+// creates a JSON Merge Patch that transforms a into b. because null members
+// of a merge patch remove keys, null values inside objects in b are not
+// reproduced by the patch.
+func CreateMergePatch(a, b *JsonTree) (*JsonTree, error) {
+	aval, err := a.value()
+	if err != nil {
+		return nil, err
+	}
+	bval, err := b.value()
+	if err != nil {
+		return nil, err
+	}
+	patch := newTree(createMergePatch(aval, bval))
+	patch.getType()
+	return patch, nil
+}
+
+func createMergePatch(a, b interface{}) interface{} {
+	if !isObject(a) || !isObject(b) {
+		return copyValue(b)
+	}
+	patch := newObjectLike(b)
+	amap := objectMap(a)
+	bmap := objectMap(b)
+	for _, k := range objectKeys(a) {
+		if _, ok := bmap[k]; !ok {
+			objectSet(patch, k, nil)
+		}
+	}
+	for _, k := range objectKeys(b) {
+		aval, ok := amap[k]
+		switch {
+		case !ok:
+			objectSet(patch, k, copyValue(bmap[k]))
+		case isObject(aval) && isObject(bmap[k]):
+			sub := createMergePatch(aval, bmap[k])
+			if len(objectMap(sub)) > 0 {
+				objectSet(patch, k, sub)
+			}
+		case !valuesEqual(aval, bmap[k]):
+			objectSet(patch, k, copyValue(bmap[k]))
+		}
+	}
+	return patch
+}
+
+func isObject(val interface{}) bool {
+	switch val.(type) {
+	case map[string]interface{}, *OrderedObject:
+		return true
+	}
+	return false
+}
+
+// an empty object of the same representation as val.
+func newObjectLike(val interface{}) interface{} {
+	_, ok := val.(*OrderedObject)
+	return newObject(ok)
+}
+
+// an empty object, ordered if ordered is true.
+func newObject(ordered bool) interface{} {
+	if ordered {
+		return &OrderedObject{m: make(map[string]interface{})}
+	}
+	return make(map[string]interface{})
+}
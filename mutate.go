@@ -0,0 +1,244 @@
+// Copyright 2013, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// mutate.go [created: Sat, 17 Oct 2026]
+
+package jsontree
+
+// sets key in the object tree to a copy of value. changes are written through
+// to the tree's parent so they are visible from Root(). a nil value is stored
+// as null. returns a *PathError if tree is not an object.
+func (tree *JsonTree) Set(key string, value *JsonTree) error {
+	val, err := value.storeValue()
+	if err != nil {
+		return err
+	}
+	if err := tree.checkType(Object); err != nil {
+		return err
+	}
+	objectSet(tree.val, key, val)
+	tree.changed()
+	return nil
+}
+
+// sets the i-th element of the array tree to a copy of value. returns a
+// *PathError if tree is not an array or i is out of range.
+func (tree *JsonTree) SetIndex(i int, value *JsonTree) error {
+	val, err := value.storeValue()
+	if err != nil {
+		return err
+	}
+	if err := tree.checkType(Array); err != nil {
+		return err
+	}
+	a := tree.val.([]interface{})
+	if i < 0 || len(a) <= i {
+		return tree.pathError(ErrIndexOutOfRange)
+	}
+	a[i] = val
+	tree.changed()
+	return nil
+}
+
+// appends a copy of value to the end of the array tree. returns a *PathError
+// if tree is not an array.
+func (tree *JsonTree) Append(value *JsonTree) error {
+	val, err := value.storeValue()
+	if err != nil {
+		return err
+	}
+	if err := tree.checkType(Array); err != nil {
+		return err
+	}
+	tree.setVal(append(tree.val.([]interface{}), val))
+	return nil
+}
+
+// inserts a copy of value into the array tree so that it becomes the i-th
+// element. i may equal the length of tree, in which case Insert is equivalent
+// to Append. returns a *PathError if tree is not an array or i is out of
+// range.
+func (tree *JsonTree) Insert(i int, value *JsonTree) error {
+	val, err := value.storeValue()
+	if err != nil {
+		return err
+	}
+	if err := tree.checkType(Array); err != nil {
+		return err
+	}
+	a := tree.val.([]interface{})
+	if i < 0 || len(a) < i {
+		return tree.pathError(ErrIndexOutOfRange)
+	}
+	a = append(a, nil)
+	copy(a[i+1:], a[i:])
+	a[i] = val
+	tree.setVal(a)
+	return nil
+}
+
+// removes key from the object tree. returns a *PathError if tree is not an
+// object or key does not exist.
+func (tree *JsonTree) Delete(key string) error {
+	if err := tree.checkType(Object); err != nil {
+		return err
+	}
+	if !objectDelete(tree.val, key) {
+		return tree.pathError(ErrNoExist)
+	}
+	tree.changed()
+	return nil
+}
+
+// removes the i-th element from the array tree, shifting subsequent elements
+// down. returns a *PathError if tree is not an array or i is out of range.
+func (tree *JsonTree) DeleteIndex(i int) error {
+	if err := tree.checkType(Array); err != nil {
+		return err
+	}
+	a := tree.val.([]interface{})
+	if i < 0 || len(a) <= i {
+		return tree.pathError(ErrIndexOutOfRange)
+	}
+	a = append(a[:i:i], a[i+1:]...)
+	tree.setVal(a)
+	return nil
+}
+
+// a copy of the value of tree to store inside another tree, so that the trees
+// do not share objects or arrays and a tree can be stored inside itself.
+func (tree *JsonTree) storeValue() (interface{}, error) {
+	val, err := tree.value()
+	if err != nil {
+		return nil, err
+	}
+	return copyValue(val), nil
+}
+
+// the value to store when tree is placed inside another tree.
+func (tree *JsonTree) value() (interface{}, error) {
+	if tree == nil {
+		return nil, nil
+	}
+	if !tree.init {
+		return nil, tree.pathError(ErrUninitialized)
+	}
+	if tree.typ == Error {
+		return nil, *tree.err
+	}
+	if err := tree.load(); err != nil {
+		return nil, err
+	}
+	return tree.val, nil
+}
+
+// returns a *PathError if tree is not of the expected type.
+func (tree *JsonTree) checkType(expected JsonType) error {
+	switch {
+	case !tree.init:
+		return tree.pathError(ErrUninitialized)
+	case tree.typ == Error:
+		return *tree.err
+	case tree.typ != expected:
+		return tree.pathError(&TypeError{Expected: expected, Actual: tree.typ})
+	}
+	return tree.load()
+}
+
+// replaces the value of tree and writes it through to the container in the
+// parent tree. needed when an operation reallocates a slice.
+func (tree *JsonTree) setVal(val interface{}) {
+	tree.val = val
+	if tree.source != nil {
+		tree.source.replace(val)
+	}
+	parent := tree.parent
+	if parent == nil {
+		return
+	}
+	if parent.source != nil {
+		parent.source.set(tree.key, tree.index, val)
+		return
+	}
+	switch pval := parent.val.(type) {
+	case []interface{}:
+		if 0 <= tree.index && tree.index < len(pval) {
+			pval[tree.index] = val
+			parent.changed()
+		}
+	case map[string]interface{}, *OrderedObject:
+		if tree.index < 0 {
+			objectSet(pval, tree.key, val)
+			parent.changed()
+		}
+	case *lazyValue:
+		pval.set(tree.key, tree.index, val)
+	}
+}
+
+// replaces the value of tree with a copy of value. changes are written
+// through to the tree's parent so they are visible from Root(). a nil value is
+// stored as null. returns a *PathError if tree is uninitialized or has an error.
+func (tree *JsonTree) SetValue(value *JsonTree) error {
+	val, err := value.storeValue()
+	if err != nil {
+		return err
+	}
+	if !tree.init {
+		return tree.pathError(ErrUninitialized)
+	}
+	if tree.typ == Error {
+		return *tree.err
+	}
+	tree.setVal(val)
+	tree.getType()
+	return nil
+}
+
+// a deep copy of tree with no parent. changes to the copy do not affect tree.
+func (tree *JsonTree) Copy() *JsonTree {
+	val := tree.val
+	if tree.source != nil && tree.source.isContainer() {
+		val = tree.source
+	}
+	c := newTree(copyValue(val))
+	c.ordered = tree.ordered
+	c.useNumber = tree.useNumber
+	c.lazy = tree.lazy
+	c.err = tree.err
+	if tree.init {
+		c.getType()
+	}
+	return c
+}
+
+func copyValue(val interface{}) interface{} {
+	switch val := val.(type) {
+	case []interface{}:
+		a := make([]interface{}, len(val))
+		for i := range val {
+			a[i] = copyValue(val[i])
+		}
+		return a
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, v := range val {
+			m[k] = copyValue(v)
+		}
+		return m
+	case *OrderedObject:
+		o := &OrderedObject{
+			keys: make([]string, len(val.keys)),
+			m:    make(map[string]interface{}, len(val.m)),
+		}
+		copy(o.keys, val.keys)
+		for k, v := range val.m {
+			o.m[k] = copyValue(v)
+		}
+		return o
+	case *lazyValue:
+		return val.copy()
+	}
+	return val
+}
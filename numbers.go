@@ -0,0 +1,170 @@
+// Copyright 2013, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// numbers.go [created: Sat, 17 Oct 2026]
+
+package jsontree
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// causes UnmarshalJSON to decode numbers as json.Number values instead of
+// float64. the original digits are kept so integers too large for a float64
+// can be read exactly with Int64, Uint64 or BigInt and are marshaled
+// unchanged. UseNumber must be called before the tree is initialized.
+func (tree *JsonTree) UseNumber() {
+	tree.useNumber = true
+}
+
+// converts tree to a float64. equivalent to Number.
+func (tree *JsonTree) Float64() (float64, error) {
+	return tree.Number()
+}
+
+// converts tree to an int64. returns a *PathError if tree is not a number, is
+// not an integer, or overflows an int64.
+func (tree *JsonTree) Int64() (int64, error) {
+	if n, ok := tree.val.(json.Number); ok && tree.typ == Number {
+		if x, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+			return x, nil
+		}
+	}
+	x, err := tree.BigInt()
+	if err != nil {
+		return 0, err
+	}
+	if !x.IsInt64() {
+		return 0, tree.pathErrorf("number %s overflows int64", tree.numberString())
+	}
+	return x.Int64(), nil
+}
+
+// converts tree to a uint64. returns a *PathError if tree is not a number, is
+// not an integer, or overflows a uint64.
+func (tree *JsonTree) Uint64() (uint64, error) {
+	x, err := tree.BigInt()
+	if err != nil {
+		return 0, err
+	}
+	if !x.IsUint64() {
+		return 0, tree.pathErrorf("number %s overflows uint64", tree.numberString())
+	}
+	return x.Uint64(), nil
+}
+
+// converts tree to a *big.Int. returns a *PathError if tree is not a number or
+// is not an integer.
+func (tree *JsonTree) BigInt() (*big.Int, error) {
+	r, err := tree.bigRat()
+	if err != nil {
+		return nil, err
+	}
+	if !r.IsInt() {
+		return nil, tree.pathErrorf("number %s is not an integer", tree.numberString())
+	}
+	return new(big.Int).Set(r.Num()), nil
+}
+
+// converts tree to a *big.Float with enough precision to hold any integer
+// written with the same number of digits. returns a *PathError if tree is not
+// a number.
+func (tree *JsonTree) BigFloat() (*big.Float, error) {
+	if err := tree.checkType(Number); err != nil {
+		return nil, err
+	}
+	if f, ok := tree.val.(float64); ok {
+		return big.NewFloat(f), nil
+	}
+	s := tree.numberString()
+	prec := uint(4 * len(s))
+	if prec < 64 {
+		prec = 64
+	}
+	x, _, err := big.ParseFloat(s, 10, prec, big.ToNearestEven)
+	if err != nil {
+		return nil, tree.pathErrorf("invalid number %s", s)
+	}
+	return x, nil
+}
+
+// the decimal representation of the number tree. numbers decoded after
+// UseNumber are returned with their original digits. returns a *PathError if
+// tree is not a number.
+func (tree *JsonTree) NumberString() (string, error) {
+	if err := tree.checkType(Number); err != nil {
+		return "", err
+	}
+	return tree.numberString(), nil
+}
+
+func (tree *JsonTree) numberString() string {
+	switch x := tree.val.(type) {
+	case json.Number:
+		return string(x)
+	case float64:
+		p, err := json.Marshal(x)
+		if err == nil {
+			return string(p)
+		}
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	}
+	return ""
+}
+
+// the value of a number tree that has been checked.
+func (tree *JsonTree) float64() (float64, error) {
+	switch x := tree.val.(type) {
+	case float64:
+		return x, nil
+	case json.Number:
+		f, err := strconv.ParseFloat(string(x), 64)
+		if err != nil {
+			return 0, tree.pathErrorf("number %s overflows float64", x)
+		}
+		return f, nil
+	}
+	return 0, tree.pathError(&TypeError{Expected: Number, Actual: tree.Type()})
+}
+
+// the exact value of the number tree.
+func (tree *JsonTree) bigRat() (*big.Rat, error) {
+	if err := tree.checkType(Number); err != nil {
+		return nil, err
+	}
+	switch x := tree.val.(type) {
+	case float64:
+		if math.IsInf(x, 0) || math.IsNaN(x) {
+			return nil, tree.pathErrorf("invalid number %v", x)
+		}
+		return new(big.Rat).SetFloat64(x), nil
+	case json.Number:
+		if !exponentInRange(string(x)) {
+			return nil, tree.pathErrorf("number %s exponent out of range", x)
+		}
+		r, ok := new(big.Rat).SetString(string(x))
+		if !ok {
+			return nil, tree.pathErrorf("invalid number %s", x)
+		}
+		return r, nil
+	}
+	return nil, tree.pathError(&TypeError{Expected: Number, Actual: tree.Type()})
+}
+
+// the largest decimal exponent bigRat will expand. keeps hostile input like
+// 1e999999999 from allocating huge integers.
+const maxExponent = 1 << 12
+
+func exponentInRange(s string) bool {
+	i := strings.IndexAny(s, "eE")
+	if i < 0 {
+		return true
+	}
+	exp, err := strconv.Atoi(strings.TrimPrefix(s[i+1:], "+"))
+	return err == nil && -maxExponent <= exp && exp <= maxExponent
+}
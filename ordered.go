@@ -0,0 +1,241 @@
+// Copyright 2013, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// ordered.go [created: Sat, 17 Oct 2026]
+
+package jsontree
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+)
+
+// creates an empty *JsonTree that keeps the order of object keys when
+// initialized with json.Unmarshal(). objects in the tree are represented by
+// *OrderedObject values and are encoded with their keys in source order.
+func NewOrdered() *JsonTree {
+	tree := New()
+	tree.ordered = true
+	return tree
+}
+
+// a JSON object that remembers the order in which its keys were added.
+type OrderedObject struct {
+	keys []string
+	m    map[string]interface{}
+}
+
+// the keys of o in order.
+func (o *OrderedObject) Keys() []string {
+	keys := make([]string, len(o.keys))
+	copy(keys, o.keys)
+	return keys
+}
+
+// the members of o. the map must not be modified directly.
+func (o *OrderedObject) Map() map[string]interface{} {
+	return o.m
+}
+
+// implements json.Marshaler
+func (o *OrderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		p, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(p)
+		buf.WriteByte(':')
+		p, err = json.Marshal(o.m[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(p)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (o *OrderedObject) set(key string, val interface{}) {
+	if _, ok := o.m[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.m[key] = val
+}
+
+func (o *OrderedObject) delete(key string) bool {
+	if _, ok := o.m[key]; !ok {
+		return false
+	}
+	delete(o.m, key)
+	for i := range o.keys {
+		if o.keys[i] == key {
+			o.keys = append(o.keys[:i], o.keys[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// the keys of the object tree. keys of ordered objects are returned in order,
+// other keys are sorted. returns a *PathError if tree is not an object.
+func (tree *JsonTree) Keys() ([]string, error) {
+	if err := tree.checkType(Object); err != nil {
+		return nil, err
+	}
+	return objectKeys(tree.val), nil
+}
+
+// the keys of an object value, in order if the object is ordered.
+func objectKeys(val interface{}) []string {
+	if o, ok := val.(*OrderedObject); ok {
+		return o.Keys()
+	}
+	m := val.(map[string]interface{})
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// the members of an object value.
+func objectMap(val interface{}) map[string]interface{} {
+	if o, ok := val.(*OrderedObject); ok {
+		return o.m
+	}
+	return val.(map[string]interface{})
+}
+
+func objectSet(val interface{}, key string, v interface{}) {
+	if o, ok := val.(*OrderedObject); ok {
+		o.set(key, v)
+		return
+	}
+	val.(map[string]interface{})[key] = v
+}
+
+func objectDelete(val interface{}, key string) bool {
+	if o, ok := val.(*OrderedObject); ok {
+		return o.delete(key)
+	}
+	m := val.(map[string]interface{})
+	if _, ok := m[key]; !ok {
+		return false
+	}
+	delete(m, key)
+	return true
+}
+
+// decodes p into tree.val using the token stream, honoring the ordered,
+// useNumber and trackPos options.
+func (tree *JsonTree) unmarshalTokens(p []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(p))
+	if tree.useNumber {
+		dec.UseNumber()
+	}
+	var val interface{}
+	var pos *posNode
+	var err error
+	if tree.ordered || tree.trackPos != nil {
+		d := &tokenDecoder{dec: dec, ordered: tree.ordered}
+		if tree.trackPos != nil {
+			d.p = p
+			d.src = newSource(*tree.trackPos, p)
+		}
+		val, pos, err = d.decode()
+	} else {
+		err = dec.Decode(&val)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("invalid data after top-level value")
+	}
+	tree.val = val
+	tree.pos = pos
+	return nil
+}
+
+// decodes values from a token stream. objects are represented as
+// *OrderedObject if ordered is true. if src is not nil the position of each
+// value in p is recorded.
+type tokenDecoder struct {
+	dec     *json.Decoder
+	ordered bool
+	p       []byte
+	src     *source
+}
+
+// decodes the next value and its positions, which are nil unless tracked.
+func (d *tokenDecoder) decode() (interface{}, *posNode, error) {
+	var pos *posNode
+	if d.src != nil {
+		pos = &posNode{src: d.src, off: d.next(), keyOff: -1}
+	}
+	tok, err := d.dec.Token()
+	if err != nil {
+		return nil, nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		var obj interface{} = make(map[string]interface{})
+		if d.ordered {
+			obj = &OrderedObject{m: make(map[string]interface{})}
+		}
+		for d.dec.More() {
+			keyOff := d.next()
+			tok, err := d.dec.Token()
+			if err != nil {
+				return nil, nil, err
+			}
+			val, child, err := d.decode()
+			if err != nil {
+				return nil, nil, err
+			}
+			objectSet(obj, tok.(string), val)
+			if pos != nil {
+				child.keyOff = keyOff
+				pos.setMember(tok.(string), child)
+			}
+		}
+		_, err := d.dec.Token()
+		return obj, pos, err
+	case json.Delim('['):
+		a := make([]interface{}, 0)
+		for d.dec.More() {
+			val, child, err := d.decode()
+			if err != nil {
+				return nil, nil, err
+			}
+			a = append(a, val)
+			if pos != nil {
+				pos.elems = append(pos.elems, child)
+			}
+		}
+		_, err := d.dec.Token()
+		return a, pos, err
+	}
+	return tok, pos, nil
+}
+
+// the offset in p at which the next token begins.
+func (d *tokenDecoder) next() int {
+	off := int(d.dec.InputOffset())
+	for off < len(d.p) && strings.IndexByte(" \t\r\n,:", d.p[off]) >= 0 {
+		off++
+	}
+	return off
+}
@@ -0,0 +1,75 @@
+// Copyright 2013, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// create.go [created: Sat, 17 Oct 2026]
+
+package patch
+
+import (
+	"strconv"
+
+	"github.com/bmatsuo/go-jsontree"
+)
+
+// creates a patch that transforms a into b. objects are compared member by
+// member and arrays element by element; elements are only added or removed at
+// the end of an array.
+func Create(a, b *jsontree.JsonTree) (Patch, error) {
+	if _, err := a.Interface(); err != nil {
+		return nil, err
+	}
+	if _, err := b.Interface(); err != nil {
+		return nil, err
+	}
+	return diff(nil, jsontree.Pointer{}, a, b), nil
+}
+
+func diff(patch Patch, path jsontree.Pointer, a, b *jsontree.JsonTree) Patch {
+	if a.Type() != b.Type() {
+		return append(patch, Operation{Op: "replace", Path: path.String(), Value: b.Copy()})
+	}
+	switch a.Type() {
+	case jsontree.Object:
+		akeys, _ := a.Keys()
+		bkeys, _ := b.Keys()
+		for _, k := range akeys {
+			if b.Get(k).Err() != nil {
+				patch = append(patch, Operation{Op: "remove", Path: child(path, k).String()})
+			}
+		}
+		for _, k := range bkeys {
+			aval := a.Get(k)
+			if aval.Err() != nil {
+				patch = append(patch, Operation{Op: "add", Path: child(path, k).String(), Value: b.Get(k).Copy()})
+				continue
+			}
+			patch = diff(patch, child(path, k), aval, b.Get(k))
+		}
+		return patch
+	case jsontree.Array:
+		alen, _ := a.Len()
+		blen, _ := b.Len()
+		for i := 0; i < alen && i < blen; i++ {
+			patch = diff(patch, child(path, strconv.Itoa(i)), a.GetIndex(i), b.GetIndex(i))
+		}
+		for i := alen - 1; i >= blen; i-- {
+			patch = append(patch, Operation{Op: "remove", Path: child(path, strconv.Itoa(i)).String()})
+		}
+		for i := alen; i < blen; i++ {
+			patch = append(patch, Operation{Op: "add", Path: child(path, "-").String(), Value: b.GetIndex(i).Copy()})
+		}
+		return patch
+	}
+	if !jsontree.Equal(a, b) {
+		patch = append(patch, Operation{Op: "replace", Path: path.String(), Value: b.Copy()})
+	}
+	return patch
+}
+
+// a copy of path extended by tok.
+func child(path jsontree.Pointer, tok string) jsontree.Pointer {
+	c := make(jsontree.Pointer, len(path), len(path)+1)
+	copy(c, path)
+	return append(c, tok)
+}
@@ -0,0 +1,227 @@
+// Copyright 2013, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// patch.go [created: Sat, 17 Oct 2026]
+
+/*
+patch applies and generates JSON Patch documents (RFC 6902) for jsontree
+structures.
+
+	http://tools.ietf.org/html/rfc6902
+
+patches are applied atomically. if any operation fails the tree is left
+unchanged and a *jsontree.PathError describing the failed operation is
+returned.
+*/
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/bmatsuo/go-jsontree"
+)
+
+// a single JSON Patch operation. Value is nil if the operation has no value
+// member.
+type Operation struct {
+	Op    string             `json:"op"`
+	Path  string             `json:"path"`
+	From  string             `json:"from,omitempty"`
+	Value *jsontree.JsonTree `json:"value,omitempty"`
+}
+
+// implements json.Unmarshaler. a null value member is distinguished from a
+// missing one.
+func (op *Operation) UnmarshalJSON(p []byte) error {
+	var raw struct {
+		Op    string          `json:"op"`
+		Path  *string         `json:"path"`
+		From  *string         `json:"from"`
+		Value json.RawMessage `json:"value"`
+	}
+	err := json.Unmarshal(p, &raw)
+	if err != nil {
+		return err
+	}
+	if raw.Path == nil {
+		return errors.New("missing path")
+	}
+	*op = Operation{Op: raw.Op, Path: *raw.Path}
+	switch {
+	case raw.From != nil:
+		op.From = *raw.From
+	case raw.Op == "move" || raw.Op == "copy":
+		return errors.New("missing from")
+	}
+	if raw.Value != nil {
+		op.Value = jsontree.NewOrdered()
+		op.Value.UseNumber()
+		err = op.Value.UnmarshalJSON(raw.Value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// a JSON Patch document.
+type Patch []Operation
+
+// parses a JSON Patch document.
+func Parse(p []byte) (Patch, error) {
+	var patch Patch
+	err := json.Unmarshal(p, &patch)
+	if err != nil {
+		return nil, err
+	}
+	return patch, nil
+}
+
+// applies each operation in patch to tree in order. if an operation fails
+// tree is not modified and a *jsontree.PathError is returned whose Path is the
+// path of the failed operation.
+func (patch Patch) Apply(tree *jsontree.JsonTree) error {
+	doc := tree.Copy()
+	for i, op := range patch {
+		var err error
+		doc, err = op.apply(doc)
+		if err != nil {
+			return &jsontree.PathError{
+				Path: op.Path,
+				Err:  fmt.Errorf("operation %d (%s): %w", i, op.Op, err),
+			}
+		}
+	}
+	return tree.SetValue(doc)
+}
+
+// applies op to doc and returns the resulting document.
+func (op Operation) apply(doc *jsontree.JsonTree) (*jsontree.JsonTree, error) {
+	path, err := jsontree.ParsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, errors.New("missing value")
+		}
+	}
+	switch op.Op {
+	case "add":
+		return add(doc, path, op.Value.Copy())
+	case "remove":
+		_, err := remove(doc, path)
+		return doc, err
+	case "replace":
+		if len(path) == 0 {
+			return op.Value.Copy(), nil
+		}
+		val := path.Lookup(doc)
+		if err := val.Err(); err != nil {
+			return nil, err
+		}
+		return doc, val.SetValue(op.Value.Copy())
+	case "move":
+		from, err := jsontree.ParsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		if len(from) < len(path) && isPrefix(from, path) {
+			return nil, errors.New("can not move a value into itself")
+		}
+		val, err := remove(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, val)
+	case "copy":
+		from, err := jsontree.ParsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		val := from.Lookup(doc)
+		if err := val.Err(); err != nil {
+			return nil, err
+		}
+		return add(doc, path, val.Copy())
+	case "test":
+		val := path.Lookup(doc)
+		if err := val.Err(); err != nil {
+			return nil, err
+		}
+		if !jsontree.Equal(val, op.Value) {
+			return nil, errors.New("test failed")
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unknown operation %q", op.Op)
+}
+
+// adds val to doc at path, returning the new document.
+func add(doc *jsontree.JsonTree, path jsontree.Pointer, val *jsontree.JsonTree) (*jsontree.JsonTree, error) {
+	if len(path) == 0 {
+		return val, nil
+	}
+	parent := path[:len(path)-1].Lookup(doc)
+	if err := parent.Err(); err != nil {
+		return nil, err
+	}
+	tok := path[len(path)-1]
+	if parent.Type() != jsontree.Array {
+		return doc, parent.Set(tok, val)
+	}
+	if tok == "-" {
+		return doc, parent.Append(val)
+	}
+	i, err := arrayIndex(tok)
+	if err != nil {
+		return nil, err
+	}
+	return doc, parent.Insert(i, val)
+}
+
+// removes the value at path from doc, returning the removed value.
+func remove(doc *jsontree.JsonTree, path jsontree.Pointer) (*jsontree.JsonTree, error) {
+	if len(path) == 0 {
+		return nil, errors.New("can not remove the root")
+	}
+	val := path.Lookup(doc)
+	if err := val.Err(); err != nil {
+		return nil, err
+	}
+	parent := val.Parent()
+	tok := path[len(path)-1]
+	if parent.Type() != jsontree.Array {
+		return val, parent.Delete(tok)
+	}
+	i, err := arrayIndex(tok)
+	if err != nil {
+		return nil, err
+	}
+	return val, parent.DeleteIndex(i)
+}
+
+func arrayIndex(tok string) (int, error) {
+	if tok == "" || (tok[0] == '0' && len(tok) > 1) || tok[0] == '-' || tok[0] == '+' {
+		return 0, fmt.Errorf("invalid array index %q", tok)
+	}
+	i, err := strconv.Atoi(tok)
+	if err != nil {
+		return 0, fmt.Errorf("invalid array index %q", tok)
+	}
+	return i, nil
+}
+
+func isPrefix(prefix, path jsontree.Pointer) bool {
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,140 @@
+// Copyright 2013, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// patch_test.go [created: Sat, 17 Oct 2026]
+
+package patch
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/bmatsuo/go-jsontree"
+)
+
+func tree(t *testing.T, js string) *jsontree.JsonTree {
+	tree := jsontree.NewOrdered()
+	tree.UseNumber()
+	err := tree.UnmarshalJSON([]byte(js))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+func testJSON(t *testing.T, tree *jsontree.JsonTree, expect string) {
+	p, err := tree.MarshalJSON()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(p) != expect {
+		t.Errorf("expected %s got %s", expect, p)
+	}
+}
+
+func TestApply(t *testing.T) {
+	for _, test := range []struct{ doc, patch, expect string }{
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"foo":"bar","baz":"qux"}`},
+		{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc"]}]`, `{"foo":["bar",["abc"]]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			`[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{`{"a":{"b":[1]}}`, `[{"op":"copy","from":"/a/b","path":"/c"},{"op":"add","path":"/c/-","value":2}]`, `{"a":{"b":[1]},"c":[1,2]}`},
+		{`{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2.0}]`, `{"baz":"qux","foo":["a",2,"c"]}`},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`},
+		{`{"foo":null}`, `[{"op":"test","path":"/foo","value":null}]`, `{"foo":null}`},
+		{`{"foo":1}`, `[{"op":"replace","path":"","value":[1]}]`, `[1]`},
+		{`{"id":12345678901234567890}`, `[{"op":"test","path":"/id","value":12345678901234567890}]`, `{"id":12345678901234567890}`},
+	} {
+		doc := tree(t, test.doc)
+		patch, err := Parse([]byte(test.patch))
+		if err != nil {
+			t.Errorf("%s: %v", test.patch, err)
+			continue
+		}
+		err = patch.Apply(doc)
+		if err != nil {
+			t.Errorf("%s: %v", test.patch, err)
+			continue
+		}
+		testJSON(t, doc, test.expect)
+	}
+}
+
+func TestApplyError(t *testing.T) {
+	for _, test := range []struct{ patch, path string }{
+		{`[{"op":"add","path":"/a/-","value":3},{"op":"add","path":"/baz/bat","value":"qux"}]`, "/baz/bat"},
+		{`[{"op":"remove","path":"/a/-"}]`, "/a/-"},
+		{`[{"op":"replace","path":"/nope","value":1}]`, "/nope"},
+		{`[{"op":"test","path":"/s","value":"other"}]`, "/s"},
+		{`[{"op":"test","path":"/a","value":[1,2,3]}]`, "/a"},
+		{`[{"op":"move","from":"/a","path":"/a/0"}]`, "/a/0"},
+		{`[{"op":"add","path":"/a/3","value":1}]`, "/a/3"},
+		{`[{"op":"add","path":"/a/01","value":1}]`, "/a/01"},
+		{`[{"op":"add","path":"/s"}]`, "/s"},
+		{`[{"op":"frob","path":"/s"}]`, "/s"},
+		{`[{"op":"remove","path":""}]`, ""},
+	} {
+		doc := tree(t, `{"a":[1,2],"s":"x"}`)
+		patch, err := Parse([]byte(test.patch))
+		if err != nil {
+			t.Errorf("%s: %v", test.patch, err)
+			continue
+		}
+		err = patch.Apply(doc)
+		perr, ok := err.(*jsontree.PathError)
+		if !ok {
+			t.Errorf("%s: expected *PathError got %#v", test.patch, err)
+			continue
+		}
+		if perr.Path != test.path {
+			t.Errorf("%s: expected path %q got %q", test.patch, test.path, perr.Path)
+		}
+		testJSON(t, doc, `{"a":[1,2],"s":"x"}`)
+	}
+	if _, err := Parse([]byte(`[{"op":"remove"}]`)); err == nil {
+		t.Errorf("expected error for missing path")
+	}
+	for _, op := range []string{"move", "copy"} {
+		if _, err := Parse([]byte(`[{"op":"` + op + `","path":"/a"}]`)); err == nil {
+			t.Errorf("expected error for %s without from", op)
+		}
+	}
+}
+
+func TestCreate(t *testing.T) {
+	for _, test := range []struct{ a, b string }{
+		{`{"a":1,"b":[1,2,3],"c":{"d":"e"}}`, `{"b":[1,4],"c":{"d":"f","g":null},"h":true}`},
+		{`[1,2]`, `[1,2,3,4]`},
+		{`{"a":1}`, `[1]`},
+		{`{"a":{"b":1}}`, `{"a":{"b":1}}`},
+	} {
+		a, b := tree(t, test.a), tree(t, test.b)
+		patch, err := Create(a, b)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		p, _ := json.Marshal(patch)
+		err = patch.Apply(a)
+		if err != nil {
+			t.Errorf("%s: %v", p, err)
+			continue
+		}
+		testJSON(t, a, test.b)
+	}
+	patch, _ := Create(tree(t, `{"a":1}`), tree(t, `{"a":1}`))
+	if len(patch) != 0 {
+		t.Errorf("expected empty patch got %d operations", len(patch))
+	}
+	if _, err := Create(jsontree.New(), tree(t, `{"a":1}`)); err == nil {
+		t.Errorf("expected error for an uninitialized tree")
+	}
+}
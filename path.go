@@ -0,0 +1,248 @@
+// Copyright 2013, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// path.go [created: Sat, 17 Oct 2026]
+
+package jsontree
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// a step in a Path. Index is negative for object keys.
+type Segment struct {
+	Key   string
+	Index int
+}
+
+// a Segment selecting the object member key.
+func KeySegment(key string) Segment {
+	return Segment{Key: key, Index: -1}
+}
+
+// a Segment selecting the array element i.
+func IndexSegment(i int) Segment {
+	return Segment{Index: i}
+}
+
+// reports whether s selects an array element.
+func (s Segment) IsIndex() bool {
+	return s.Index >= 0
+}
+
+// the location of a node relative to the root of its tree. the empty Path
+// refers to the root.
+type Path []Segment
+
+// the location of tree relative to its root.
+func (tree *JsonTree) Path() Path {
+	n := tree.Depth()
+	p := make(Path, n)
+	for t := tree; t.parent != nil; t = t.parent {
+		n--
+		p[n] = Segment{Key: t.key, Index: t.index}
+	}
+	return p
+}
+
+// the normalized JSONPath of p, using bracket notation for every segment, as
+// in $['a']['b'][0].
+func (p Path) String() string {
+	var buf strings.Builder
+	buf.WriteByte('$')
+	for _, s := range p {
+		buf.WriteByte('[')
+		if s.IsIndex() {
+			buf.WriteString(strconv.Itoa(s.Index))
+		} else {
+			writePathKey(&buf, s.Key)
+		}
+		buf.WriteByte(']')
+	}
+	return buf.String()
+}
+
+func writePathKey(buf *strings.Builder, key string) {
+	const hex = "0123456789abcdef"
+	buf.WriteByte('\'')
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch c {
+		case '\'', '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			if c < 0x20 {
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hex[c>>4])
+				buf.WriteByte(hex[c&0xf])
+			} else {
+				buf.WriteByte(c)
+			}
+		}
+	}
+	buf.WriteByte('\'')
+}
+
+// p written as in *PathError messages, as in $.a[0].
+func (p Path) errorPath() string {
+	var buf strings.Builder
+	buf.WriteByte('$')
+	for _, s := range p {
+		if s.IsIndex() {
+			buf.WriteByte('[')
+			buf.WriteString(strconv.Itoa(s.Index))
+			buf.WriteByte(']')
+		} else {
+			buf.WriteByte('.')
+			buf.WriteString(s.Key)
+		}
+	}
+	return buf.String()
+}
+
+// creates a *PathError for err at p, with p written as in the errors returned
+// by the methods of *JsonTree.
+func NewPathError(p Path, err error) *PathError {
+	return &PathError{Path: p.errorPath(), Err: err}
+}
+
+// the JSON Pointer referring to the same location as p.
+func (p Path) Pointer() Pointer {
+	ptr := make(Pointer, len(p))
+	for i, s := range p {
+		if s.IsIndex() {
+			ptr[i] = strconv.Itoa(s.Index)
+		} else {
+			ptr[i] = s.Key
+		}
+	}
+	return ptr
+}
+
+// the *JsonTree p refers to, relative to root. if p can not be resolved the
+// Err() method of the returned *JsonTree returns a *PathError.
+func (p Path) Lookup(root *JsonTree) *JsonTree {
+	tree := root
+	for _, s := range p {
+		if s.IsIndex() {
+			tree = tree.GetIndex(s.Index)
+		} else {
+			tree = tree.Get(s.Key)
+		}
+	}
+	return tree
+}
+
+// parses a JSONPath made only of keys and indices. segments may be written
+// in bracket notation, with single or double quoted keys, or as dot separated
+// names like those in *PathError messages.
+//
+//	$['a']["b"][0]
+//	$.a.b[0]
+func ParsePath(s string) (Path, error) {
+	if !strings.HasPrefix(s, "$") {
+		return nil, fmt.Errorf("invalid path %q: must begin with \"$\"", s)
+	}
+	p := Path{}
+	for i := 1; i < len(s); {
+		switch s[i] {
+		case '.':
+			j := i + 1
+			for j < len(s) && s[j] != '.' && s[j] != '[' {
+				j++
+			}
+			if j == i+1 {
+				return nil, fmt.Errorf("invalid path %q: empty key at %d", s, i)
+			}
+			p = append(p, KeySegment(s[i+1:j]))
+			i = j
+		case '[':
+			seg, n, err := parsePathBracket(s[i+1:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %q: %v", s, err)
+			}
+			p = append(p, seg)
+			i += 1 + n
+		default:
+			return nil, fmt.Errorf("invalid path %q: unexpected %q at %d", s, s[i], i)
+		}
+	}
+	return p, nil
+}
+
+// parses the segment following "[" in s, returning the number of bytes
+// consumed including the closing "]".
+func parsePathBracket(s string) (Segment, int, error) {
+	if s == "" {
+		return Segment{}, 0, fmt.Errorf("unterminated bracket")
+	}
+	if s[0] != '\'' && s[0] != '"' {
+		end := strings.IndexByte(s, ']')
+		if end < 0 {
+			return Segment{}, 0, fmt.Errorf("unterminated bracket")
+		}
+		i, err := strconv.Atoi(s[:end])
+		if err != nil || i < 0 {
+			return Segment{}, 0, fmt.Errorf("invalid index %q", s[:end])
+		}
+		return IndexSegment(i), end + 1, nil
+	}
+	quote := s[0]
+	var key strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == quote:
+			if i+1 >= len(s) || s[i+1] != ']' {
+				return Segment{}, 0, fmt.Errorf("expected \"]\" after key")
+			}
+			return KeySegment(key.String()), i + 2, nil
+		case c != '\\':
+			key.WriteByte(c)
+			continue
+		}
+		i++
+		if i >= len(s) {
+			break
+		}
+		switch s[i] {
+		case 'b':
+			key.WriteByte('\b')
+		case 'f':
+			key.WriteByte('\f')
+		case 'n':
+			key.WriteByte('\n')
+		case 'r':
+			key.WriteByte('\r')
+		case 't':
+			key.WriteByte('\t')
+		case 'u':
+			if i+4 >= len(s) {
+				return Segment{}, 0, fmt.Errorf("invalid escape")
+			}
+			r, err := strconv.ParseUint(s[i+1:i+5], 16, 16)
+			if err != nil {
+				return Segment{}, 0, fmt.Errorf("invalid escape %q", s[i-1:i+5])
+			}
+			key.WriteRune(rune(r))
+			i += 4
+		default:
+			key.WriteByte(s[i])
+		}
+	}
+	return Segment{}, 0, fmt.Errorf("unterminated key")
+}
@@ -0,0 +1,116 @@
+// Copyright 2013, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// pointer.go [created: Sat, 17 Oct 2026]
+
+package jsontree
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// a JSON Pointer (RFC 6901). each element is an unescaped reference token.
+// the empty Pointer refers to the whole document.
+type Pointer []string
+
+// parses the string representation of a JSON Pointer. s must be empty or
+// begin with "/", and "~" may only appear in the escapes "~0" and "~1".
+func ParsePointer(s string) (Pointer, error) {
+	if s == "" {
+		return Pointer{}, nil
+	}
+	if s[0] != '/' {
+		return nil, fmt.Errorf("invalid JSON pointer %q: must begin with \"/\"", s)
+	}
+	tokens := strings.Split(s[1:], "/")
+	for i, tok := range tokens {
+		for j := 0; j < len(tok); j++ {
+			if tok[j] == '~' && (j+1 == len(tok) || (tok[j+1] != '0' && tok[j+1] != '1')) {
+				return nil, fmt.Errorf("invalid JSON pointer %q: bad escape", s)
+			}
+		}
+		tokens[i] = pointerUnescaper.Replace(tok)
+	}
+	return Pointer(tokens), nil
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+var pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+// the string representation of p with "~" and "/" escaped.
+func (p Pointer) String() string {
+	var buf strings.Builder
+	for _, tok := range p {
+		buf.WriteByte('/')
+		buf.WriteString(pointerEscaper.Replace(tok))
+	}
+	return buf.String()
+}
+
+// the *JsonTree p refers to, relative to tree. if p can not be resolved the
+// Err() method of the returned *JsonTree returns a *PathError.
+func (p Pointer) Lookup(tree *JsonTree) *JsonTree {
+	for n, tok := range p {
+		if tree.Type() != Array {
+			tree = tree.Get(tok)
+			continue
+		}
+		i, ok := pointerIndex(tok)
+		if !ok {
+			// the token can not be written as an index of a path
+			child := tree.child(tok, -1)
+			var err error = &PathError{
+				Path: p[:n+1].String(),
+				Err:  fmt.Errorf("invalid array index %q", tok),
+			}
+			child.err = &err
+			child.getType()
+			return child
+		}
+		tree = tree.GetIndex(i)
+	}
+	return tree
+}
+
+// an array index token is "0" or digits without a leading zero. the token "-"
+// refers past the end of the array and is never found.
+func pointerIndex(tok string) (int, bool) {
+	if tok == "-" {
+		return int(^uint(0) >> 1), true
+	}
+	if tok == "" || (tok[0] == '0' && len(tok) > 1) {
+		return 0, false
+	}
+	for i := 0; i < len(tok); i++ {
+		if tok[i] < '0' || '9' < tok[i] {
+			return 0, false
+		}
+	}
+	i, err := strconv.Atoi(tok)
+	if err != nil {
+		return int(^uint(0) >> 1), true
+	}
+	return i, true
+}
+
+// a *JsonTree for the value the JSON Pointer s refers to, relative to tree.
+// if s is invalid or can not be resolved the Err() method of the returned
+// *JsonTree returns a *PathError.
+func (tree *JsonTree) Pointer(s string) *JsonTree {
+	p, err := ParsePointer(s)
+	if err != nil {
+		errtree := *tree
+		errtree.setError(err)
+		errtree.getType()
+		return &errtree
+	}
+	return p.Lookup(tree)
+}
+
+// the location of tree relative to its root as a JSON Pointer string.
+func (tree *JsonTree) JSONPointer() string {
+	return tree.Path().Pointer().String()
+}
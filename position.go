@@ -0,0 +1,144 @@
+// Copyright 2013, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// position.go [created: Sat, 17 Oct 2026]
+
+package jsontree
+
+import (
+	"fmt"
+	"sort"
+)
+
+// a location in a source document. Offset is a byte offset and Line and
+// Column count from 1, with columns measured in bytes. the zero Position is
+// not valid.
+type Position struct {
+	Filename string
+	Offset   int
+	Line     int
+	Column   int
+}
+
+// reports whether pos refers to a line.
+func (pos Position) IsValid() bool {
+	return pos.Line > 0
+}
+
+// pos formatted as "file:line:column", omitting the file if it is empty.
+func (pos Position) String() string {
+	s := pos.Filename
+	if pos.IsValid() {
+		if s != "" {
+			s += ":"
+		}
+		s += fmt.Sprintf("%d:%d", pos.Line, pos.Column)
+	}
+	if s == "" {
+		s = "-"
+	}
+	return s
+}
+
+// causes UnmarshalJSON to record the source position of every value and key.
+// start is the position of the first byte given to UnmarshalJSON, and names
+// the file positions refer to. a zero Line or Column in start is treated as 1.
+// TrackPositions must be called before the tree is initialized. positions
+// describe the decoded input and are not updated when the tree is modified.
+func (tree *JsonTree) TrackPositions(start Position) {
+	tree.trackPos = &start
+}
+
+// the source position of the value of tree. returns an invalid Position if
+// positions were not tracked or tree was not decoded from the source.
+func (tree *JsonTree) Position() Position {
+	if tree.pos == nil {
+		return Position{}
+	}
+	return tree.pos.src.position(tree.pos.off)
+}
+
+// the source position of the key of the object member tree. returns an
+// invalid Position if tree is not an object member or positions were not
+// tracked.
+func (tree *JsonTree) KeyPosition() Position {
+	if tree.pos == nil || tree.pos.keyOff < 0 {
+		return Position{}
+	}
+	return tree.pos.src.position(tree.pos.keyOff)
+}
+
+// the position of tree or its nearest ancestor that has one.
+func (tree *JsonTree) errorPosition() Position {
+	for t := tree; t != nil; t = t.parent {
+		if t.pos != nil {
+			return t.Position()
+		}
+	}
+	return Position{}
+}
+
+// a decoded document.
+type source struct {
+	start Position
+	lines []int // offsets at which lines after the first begin
+}
+
+func newSource(start Position, p []byte) *source {
+	if start.Line <= 0 {
+		start.Line = 1
+	}
+	if start.Column <= 0 {
+		start.Column = 1
+	}
+	src := &source{start: start}
+	for i, c := range p {
+		if c == '\n' {
+			src.lines = append(src.lines, i+1)
+		}
+	}
+	return src
+}
+
+// the position of the byte at offset off in the document.
+func (src *source) position(off int) Position {
+	pos := Position{Filename: src.start.Filename, Offset: src.start.Offset + off}
+	n := sort.SearchInts(src.lines, off+1)
+	if n == 0 {
+		pos.Line = src.start.Line
+		pos.Column = src.start.Column + off
+	} else {
+		pos.Line = src.start.Line + n
+		pos.Column = off - src.lines[n-1] + 1
+	}
+	return pos
+}
+
+// the positions of a decoded value and its descendants. keyOff is -1 for
+// values that are not object members.
+type posNode struct {
+	src     *source
+	off     int
+	keyOff  int
+	members map[string]*posNode
+	elems   []*posNode
+}
+
+func (pos *posNode) setMember(key string, child *posNode) {
+	if pos.members == nil {
+		pos.members = make(map[string]*posNode)
+	}
+	pos.members[key] = child
+}
+
+// the positions of the member key or element i, or nil if unknown.
+func (pos *posNode) child(key string, i int) *posNode {
+	if i < 0 {
+		return pos.members[key]
+	}
+	if i < len(pos.elems) {
+		return pos.elems[i]
+	}
+	return nil
+}
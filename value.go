@@ -0,0 +1,233 @@
+// Copyright 2013, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// value.go [created: Sat, 17 Oct 2026]
+
+package jsontree
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// creates a *JsonTree holding the JSON representation of v, following the
+// rules of json.Marshal. structs are converted using their json tags, maps
+// may have string, integer or encoding.TextMarshaler keys, integers are
+// stored exactly as json.Number values, and json.Marshaler and
+// encoding.TextMarshaler values (like time.Time) are converted using their
+// methods. returns a *PathError locating any value that can not be
+// represented, like a channel, a function or a NaN.
+func FromValue(v interface{}) (*JsonTree, error) {
+	val, err := fromValue(reflect.ValueOf(v), "$", 0)
+	if err != nil {
+		return nil, err
+	}
+	tree := newTree(val)
+	tree.getType()
+	return tree, nil
+}
+
+// the nesting depth at which fromValue assumes v contains a cycle.
+const maxValueDepth = 1000
+
+var (
+	marshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+func fromValue(v reflect.Value, path string, depth int) (interface{}, error) {
+	if depth > maxValueDepth {
+		return nil, newPathErrorf(path, "value nested too deeply")
+	}
+	if !v.IsValid() {
+		return nil, nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+	}
+
+	switch t := v.Interface().(type) {
+	case *JsonTree:
+		val, err := t.value()
+		return copyValue(val), err
+	case JsonTree:
+		val, err := t.value()
+		return copyValue(val), err
+	case json.Number:
+		if t == "" {
+			return json.Number("0"), nil
+		}
+		if _, err := strconv.ParseFloat(string(t), 64); err != nil {
+			return nil, newPathErrorf(path, "invalid number %q", t)
+		}
+		return t, nil
+	}
+	if v.Kind() != reflect.Ptr && v.CanAddr() && reflect.PtrTo(v.Type()).Implements(marshalerType) {
+		v = v.Addr()
+	}
+	if v.Type().Implements(marshalerType) {
+		p, err := v.Interface().(json.Marshaler).MarshalJSON()
+		if err != nil {
+			return nil, &PathError{Path: path, Err: err}
+		}
+		dec := json.NewDecoder(bytes.NewReader(p))
+		dec.UseNumber()
+		var val interface{}
+		if err := dec.Decode(&val); err != nil {
+			return nil, &PathError{Path: path, Err: err}
+		}
+		return val, nil
+	}
+	if v.Type().Implements(textMarshalerType) {
+		p, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return nil, &PathError{Path: path, Err: err}
+		}
+		return string(p), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return fromValue(v.Elem(), path, depth+1)
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return json.Number(strconv.FormatInt(v.Int(), 10)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return json.Number(strconv.FormatUint(v.Uint(), 10)), nil
+	case reflect.Float32, reflect.Float64:
+		x := v.Float()
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return nil, newPathErrorf(path, "invalid number %v", x)
+		}
+		if v.Kind() == reflect.Float32 {
+			return json.Number(strconv.FormatFloat(x, 'g', -1, 32)), nil
+		}
+		return x, nil
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil, nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return base64.StdEncoding.EncodeToString(v.Bytes()), nil
+		}
+		fallthrough
+	case reflect.Array:
+		a := make([]interface{}, v.Len())
+		for i := range a {
+			val, err := fromValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			a[i] = val
+		}
+		return a, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+		m := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			k, err := mapKeyString(iter.Key())
+			if err != nil {
+				return nil, &PathError{Path: path, Err: err}
+			}
+			val, err := fromValue(iter.Value(), path+"."+k, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = val
+		}
+		return m, nil
+	case reflect.Struct:
+		m := make(map[string]interface{})
+		for _, f := range cachedFields(v.Type()) {
+			fv, ok := fieldValue(v, f.index)
+			if !ok || (f.omitEmpty && isEmptyValue(fv)) {
+				continue
+			}
+			val, err := fromValue(fv, path+"."+f.name, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			if f.quoted && val != nil {
+				p, err := json.Marshal(val)
+				if err != nil {
+					return nil, newPathErrorf(path+"."+f.name, "%v", err)
+				}
+				val = string(p)
+			}
+			m[f.name] = val
+		}
+		return m, nil
+	}
+	return nil, newPathErrorf(path, "unsupported type %v", v.Type())
+}
+
+// the string form of a map key, as json.Marshal writes it.
+func mapKeyString(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+	if k.Type().Implements(textMarshalerType) {
+		if k.Kind() == reflect.Ptr && k.IsNil() {
+			return "", nil
+		}
+		p, err := k.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(p), err
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("unsupported map key type %v", k.Type())
+}
+
+// the field of v at index. returns false if the field is inside a nil
+// embedded struct pointer.
+func fieldValue(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// reports whether v is empty for the purposes of the omitempty option.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
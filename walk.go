@@ -0,0 +1,73 @@
+// Copyright 2013, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// walk.go [created: Sat, 17 Oct 2026]
+
+package jsontree
+
+// tells Walk how to proceed after visiting a node.
+type WalkAction uint8
+
+const (
+	Continue     WalkAction = iota // visit the node's children and siblings
+	SkipChildren                   // do not visit the node's children
+	Stop                           // end the walk
+)
+
+// visits tree and its descendants depth-first, calling fn with each node
+// before its children. object members are visited in the order given by
+// Keys. each node has its parent set, so node.Path() and node.Depth() give its
+// location. a container that can not be decoded is passed to fn with its Err
+// set and its children are not visited. returns false if fn returned Stop.
+func Walk(tree *JsonTree, fn func(node *JsonTree) WalkAction) bool {
+	tree.load()
+	switch fn(tree) {
+	case Stop:
+		return false
+	case SkipChildren:
+		return true
+	}
+	return walkChildren(tree, func(child *JsonTree) bool {
+		return Walk(child, fn)
+	})
+}
+
+// like Walk but calls fn with each node after its children. SkipChildren is
+// treated as Continue because the children have already been visited.
+func WalkPostOrder(tree *JsonTree, fn func(node *JsonTree) WalkAction) bool {
+	ok := walkChildren(tree, func(child *JsonTree) bool {
+		return WalkPostOrder(child, fn)
+	})
+	return ok && fn(tree) != Stop
+}
+
+// calls walk with each child of tree. a tree whose value can not be decoded
+// has type Error after load, so it has no children.
+func walkChildren(tree *JsonTree, walk func(child *JsonTree) bool) bool {
+	tree.load()
+	switch tree.typ {
+	case Array:
+		for i := range tree.val.([]interface{}) {
+			if !walk(tree.GetIndex(i)) {
+				return false
+			}
+		}
+	case Object:
+		for _, k := range objectKeys(tree.val) {
+			if !walk(tree.Get(k)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// the number of ancestors of tree. the root has depth 0.
+func (tree *JsonTree) Depth() int {
+	var n int
+	for t := tree.parent; t != nil; t = t.parent {
+		n++
+	}
+	return n
+}